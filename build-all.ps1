@@ -21,29 +21,29 @@ Write-Host "`nBuilding for Windows (amd64)..." -ForegroundColor Yellow
 $env:GOOS = "windows"
 $env:GOARCH = "amd64"
 $env:CGO_ENABLED = "0"
-go build -ldflags="-s -w" -tags netgo -a -o gomoco-windows-amd64.exe main.go
+go build -ldflags="-s -w" -tags netgo -a -o gomoco-windows-amd64.exe .
 
 # Build for Linux (amd64) - Static linking
 Write-Host "Building for Linux (amd64) with static linking..." -ForegroundColor Yellow
 $env:GOOS = "linux"
 $env:GOARCH = "amd64"
-go build -ldflags="-s -w -extldflags '-static'" -tags netgo -a -o gomoco-linux-amd64 main.go
+go build -ldflags="-s -w -extldflags '-static'" -tags netgo -a -o gomoco-linux-amd64 .
 
 # Build for Linux ARM64 - Static linking
 Write-Host "Building for Linux (arm64) with static linking..." -ForegroundColor Yellow
 $env:GOARCH = "arm64"
-go build -ldflags="-s -w -extldflags '-static'" -tags netgo -a -o gomoco-linux-arm64 main.go
+go build -ldflags="-s -w -extldflags '-static'" -tags netgo -a -o gomoco-linux-arm64 .
 
 # Build for macOS (amd64)
 Write-Host "Building for macOS (amd64)..." -ForegroundColor Yellow
 $env:GOOS = "darwin"
 $env:GOARCH = "amd64"
-go build -ldflags="-s -w" -tags netgo -a -o gomoco-darwin-amd64 main.go
+go build -ldflags="-s -w" -tags netgo -a -o gomoco-darwin-amd64 .
 
 # Build for macOS ARM64 (Apple Silicon)
 Write-Host "Building for macOS (arm64)..." -ForegroundColor Yellow
 $env:GOARCH = "arm64"
-go build -ldflags="-s -w" -tags netgo -a -o gomoco-darwin-arm64 main.go
+go build -ldflags="-s -w" -tags netgo -a -o gomoco-darwin-arm64 .
 
 # Reset environment variables
 Remove-Item Env:\GOOS
@@ -19,7 +19,7 @@ $env:CGO_ENABLED = "0"
 
 Write-Host "`nCross-compiling for Linux (amd64) with static linking..." -ForegroundColor Yellow
 go mod download
-go build -ldflags="-s -w -extldflags '-static'" -tags netgo -a -o gomoco-linux-amd64 main.go
+go build -ldflags="-s -w -extldflags '-static'" -tags netgo -a -o gomoco-linux-amd64 .
 
 # Reset environment variables
 Remove-Item Env:\GOOS
@@ -16,7 +16,7 @@ Set-Location ..
 Write-Host "`nBuilding backend..." -ForegroundColor Yellow
 $env:CGO_ENABLED = "0"
 go mod download
-go build -ldflags="-s -w" -tags netgo -a -o gomoco.exe main.go
+go build -ldflags="-s -w" -tags netgo -a -o gomoco.exe .
 Remove-Item Env:\CGO_ENABLED -ErrorAction SilentlyContinue
 
 Write-Host "`nBuild completed successfully!" -ForegroundColor Green
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/api"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/storage"
+	"github.com/Bahtya/Gomoco/internal/validation"
+)
+
+// usage prints the commands and the flags of serve
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, `Usage: gomoco [command] [flags]
+
+Commands:
+  serve      Run the mocks with the management API and web UI (default)
+  run        Run the mocks of a config file without saving changes to it
+  validate   Check config files without starting anything
+  ctl        Manage the mocks of a running gomoco
+  version    Show version information
+
+Run "gomoco <command> -h" for the flags of run, validate and ctl.
+
+Flags of serve:
+`)
+	flag.PrintDefaults()
+}
+
+// printVersion prints the version information
+func printVersion() {
+	fmt.Printf("%s v%s\n", appName, appVersion)
+	fmt.Println("A lightweight mock server written in Go")
+}
+
+// runCommand starts the mocks of a config file, optionally with the
+// management API, until SIGINT or SIGTERM. The file is never written to.
+// It returns 1 if the file is invalid or any mock fails to start.
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	file := fs.String("f", "", "Mocks config file, or directory of *.yaml files (required)")
+	withAPI := fs.Bool("api", false, "Also serve the management API on -port; changes are kept in memory only")
+	withUI := fs.Bool("ui", false, "Also serve the web UI (implies -api)")
+	fs.IntVar(port, "port", 8080, "Management API port")
+	fs.StringVar(mockBind, "mock-bind", "", "Address mocks without a bind_host listen on (default all interfaces)")
+	fs.StringVar(mockPorts, "mock-ports", fmt.Sprintf("%d-%d", server.DefaultMinPort, server.DefaultMaxPort), "Range ports of mocks created with port 0 are picked from")
+	fs.StringVar(masterKey, "master-key-file", "", "File holding the key credentials are encrypted with (or env GOMOCO_MASTER_KEY)")
+	fs.StringVar(dataDir, "data-dir", ".", "Directory FTP/SFTP root directories set through the API must be inside (empty allows any)")
+	fs.DurationVar(drainTime, "shutdown-timeout", 10*time.Second, "How long to wait for connections to drain on shutdown")
+	fs.Parse(args)
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "run: -f is required")
+		fs.Usage()
+		return 2
+	}
+	minPort, maxPort, err := validation.PortRange(*mockPorts)
+	if err != nil {
+		log.Printf("Invalid -mock-ports: %v", err)
+		return 2
+	}
+	if err := loadMasterKey(); err != nil {
+		log.Printf("Failed to load master key: %v", err)
+		return 1
+	}
+
+	store, mocks, err := loadConfig(*file)
+	if err != nil {
+		log.Printf("%v", err)
+		return 1
+	}
+	if len(mocks) == 0 {
+		log.Printf("No mocks defined in %s", *file)
+		return 1
+	}
+	if problems := checkMocks(mocks); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("Invalid mock %s", problem)
+		}
+		return 1
+	}
+
+	serveAPI := *withAPI || *withUI
+	opts := server.Options{BindHost: *mockBind, MinPort: minPort, MaxPort: maxPort, DataDir: *dataDir}
+	if serveAPI {
+		opts.ReservedPorts = map[int]string{*port: "management API"}
+	}
+	manager := server.NewManager(store, opts)
+
+	running := 0
+	for _, mock := range manager.List() {
+		if mock.Status == "running" {
+			running++
+		}
+	}
+	if running < len(mocks) {
+		log.Printf("%d of %d mocks failed to start", len(mocks)-running, len(mocks))
+		ctx, cancel := context.WithTimeout(context.Background(), *drainTime)
+		defer cancel()
+		manager.Shutdown(ctx)
+		return 1
+	}
+	log.Printf("Running %d mocks from %s (read-only)", running, *file)
+
+	var apiServer *api.Server
+	addr := fmt.Sprintf(":%d", *port)
+	if serveAPI {
+		authenticators, err := newAuthenticators()
+		if err != nil {
+			log.Printf("Failed to configure authentication: %v", err)
+			return 1
+		}
+		apiServer = api.NewServer(manager, staticFiles, api.Options{
+			Auth:      authenticators,
+			DisableUI: !*withUI,
+			Version:   appVersion,
+		})
+		log.Printf("Serving the management API on http://localhost%s", addr)
+	}
+
+	waitForShutdown(manager, apiServer, addr, nil)
+	return 0
+}
+
+// validateCommand checks the mocks of config files without starting them.
+// It returns 1 if any file cannot be loaded or holds an invalid mock.
+func validateCommand(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	file := fs.String("f", "", "Mocks config file, or directory of *.yaml files; more may follow as arguments")
+	fs.StringVar(masterKey, "master-key-file", "", "File holding the key credentials are encrypted with (or env GOMOCO_MASTER_KEY)")
+	fs.Parse(args)
+
+	files := fs.Args()
+	if *file != "" {
+		files = append([]string{*file}, files...)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "validate: no config file given")
+		fs.Usage()
+		return 2
+	}
+	if err := loadMasterKey(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load master key: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, path := range files {
+		_, mocks, err := loadConfig(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		problems := checkMocks(mocks)
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", path, problem)
+		}
+		if len(problems) > 0 {
+			status = 1
+			continue
+		}
+		fmt.Printf("%s: OK (%d mocks)\n", path, len(mocks))
+	}
+	return status
+}
+
+// loadConfig opens a config file or directory read-only and loads its mocks
+func loadConfig(path string) (storage.Storage, []*models.MockAPI, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var store *storage.YAMLStorage
+	if info.IsDir() {
+		store, err = storage.NewDirStorage(path)
+	} else {
+		store, err = storage.NewStorage(path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	readOnly := storage.NewReadOnlyStorage(store)
+	mocks, err := readOnly.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	return readOnly, mocks, nil
+}
+
+// checkMocks validates mock definitions and describes every invalid field
+func checkMocks(mocks []*models.MockAPI) []string {
+	var problems []string
+	for _, mock := range mocks {
+		err := validation.Mock(mock)
+		var fieldErrs validation.Errors
+		if errors.As(err, &fieldErrs) {
+			for _, fieldErr := range fieldErrs {
+				problems = append(problems, fmt.Sprintf("%q: %s: %s", mock.Name, fieldErr.Field, fieldErr.Message))
+			}
+		} else if err != nil {
+			problems = append(problems, fmt.Sprintf("%q: %v", mock.Name, err))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,624 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Bahtya/Gomoco/pkg/client"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ctlUsage describes the ctl subcommands
+const ctlUsage = `Usage: gomoco ctl [flags] <command> [args]
+
+Manage the mocks of a running gomoco through its management API.
+
+Commands:
+  list                          List mocks (-workspace, -protocol, -status, -q, -tag, -label)
+  get <mock>                    Show a mock
+  create -f <file>              Create the mocks of a YAML or JSON file
+  update <mock> -f <file>       Replace a mock with the definition in a file
+  update <mock> field=value...  Change single fields, e.g. status_code=503 or
+                                headers:='{"Retry-After": "5"}' for JSON values
+  delete <mock>...              Delete mocks
+  start <mock>...               Start mocks
+  stop <mock>...                Stop mocks
+  files <mock> [dir]            List the files of an FTP/SFTP mock
+  upload <mock> <file> [dir]    Upload a file to an FTP/SFTP mock
+  download <mock> <path> [file] Download a file from an FTP/SFTP mock ("-" writes to stdout)
+
+Mocks are given by ID or by name.
+
+Flags:
+`
+
+// ctl calls the management API of a remote gomoco
+type ctl struct {
+	server   string
+	token    string
+	username string
+	password string
+	output   string
+	timeout  time.Duration
+
+	client *client.Client
+	out    io.Writer
+}
+
+// ctlCommand runs a ctl subcommand and returns the exit status
+func ctlCommand(args []string) int {
+	c := &ctl{
+		server:   envOr("GOMOCO_SERVER", "http://localhost:8080"),
+		username: os.Getenv("GOMOCO_USERNAME"),
+		output:   "table",
+		out:      os.Stdout,
+	}
+	fs := c.flagSet("ctl")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), ctlUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	commands := map[string]func(ctx context.Context, args []string) error{
+		"list":     c.list,
+		"get":      c.get,
+		"create":   c.create,
+		"update":   c.update,
+		"delete":   c.delete,
+		"start":    c.start,
+		"stop":     c.stop,
+		"files":    c.files,
+		"upload":   c.upload,
+		"download": c.download,
+	}
+	name := fs.Arg(0)
+	command, exists := commands[name]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "Unknown ctl command %q\n\n", name)
+		fs.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := command(ctx, fs.Args()[1:]); err != nil {
+		printCtlError(name, err)
+		return 1
+	}
+	return 0
+}
+
+// flagSet returns a flag set with the connection and output flags, so they
+// may be given before or after the command. Flags given before the command
+// are the defaults of those after it, except for the token and password,
+// which have no default so that -h does not print them.
+func (c *ctl) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&c.server, "server", c.server, "Base URL of the gomoco server (or env GOMOCO_SERVER)")
+	fs.Func("token", "API `token` (or env GOMOCO_TOKEN)", secretFlag(&c.token))
+	fs.StringVar(&c.username, "username", c.username, "Basic auth user (or env GOMOCO_USERNAME)")
+	fs.Func("password", "Basic auth `password` (or env GOMOCO_PASSWORD)", secretFlag(&c.password))
+	fs.StringVar(&c.output, "o", c.output, "Output format: table or json")
+	fs.DurationVar(&c.timeout, "timeout", c.timeout, "Give up after this long (default no limit)")
+	return fs
+}
+
+// secretFlag returns the setter of a flag kept in *value, which stays set
+// across the flag sets of ctl and its command
+func secretFlag(value *string) func(string) error {
+	return func(s string) error {
+		*value = s
+		return nil
+	}
+}
+
+// parse parses the flags of a command, which may be mixed with its
+// arguments, and connects to the server
+func (c *ctl) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if c.output != "table" && c.output != "json" {
+		return nil, fmt.Errorf("unsupported output format: %s", c.output)
+	}
+	// Secrets are not flag defaults, which -h would print
+	if c.token == "" {
+		c.token = os.Getenv("GOMOCO_TOKEN")
+	}
+	if c.password == "" {
+		c.password = os.Getenv("GOMOCO_PASSWORD")
+	}
+	c.client = client.New(c.server, client.Options{
+		HTTPClient: &http.Client{Timeout: c.timeout},
+		Token:      c.token,
+		Username:   c.username,
+		Password:   c.password,
+		User:       os.Getenv("USER"),
+	})
+	return positional, nil
+}
+
+// list lists the mocks matching the filter flags
+func (c *ctl) list(ctx context.Context, args []string) error {
+	fs := c.flagSet("list")
+	var opts client.ListOptions
+	fs.StringVar(&opts.Workspace, "workspace", "", "Only mocks of this workspace")
+	fs.StringVar(&opts.Protocol, "protocol", "", "Only mocks of this protocol")
+	fs.StringVar(&opts.Status, "status", "", "Only running or stopped mocks")
+	fs.StringVar(&opts.Search, "q", "", "Only mocks whose name, path or content contain this text")
+	fs.StringVar(&opts.Sort, "sort", "", "Sort by name, port, protocol, status or workspace; prefix - to reverse")
+	tags := fs.String("tag", "", "Only mocks with all of these comma separated tags")
+	labels := fs.String("label", "", "Only mocks with all of these comma separated key=value labels")
+	if _, err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if *tags != "" {
+		opts.Tags = strings.Split(*tags, ",")
+	}
+	if *labels != "" {
+		opts.Labels = make(map[string]string)
+		for _, label := range strings.Split(*labels, ",") {
+			key, value, found := strings.Cut(label, "=")
+			if !found {
+				return fmt.Errorf("invalid label selector: %s (expected key=value)", label)
+			}
+			opts.Labels[key] = value
+		}
+	}
+
+	mocks, _, err := c.client.ListMocks(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return c.printMocks(mocks)
+}
+
+// get shows a single mock
+func (c *ctl) get(ctx context.Context, args []string) error {
+	args, err := c.parse(c.flagSet("get"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("expected one mock")
+	}
+
+	mock, err := c.resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(mock)
+	}
+	return c.printMocks([]*client.MockAPI{mock})
+}
+
+// create creates the mocks of a file. Creation stops at the first mock
+// the server rejects.
+func (c *ctl) create(ctx context.Context, args []string) error {
+	fs := c.flagSet("create")
+	file := fs.String("f", "", "YAML or JSON file holding a mock, a list of mocks or a config with a mocks key (- reads stdin)")
+	if _, err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("-f is required")
+	}
+
+	var reqs []*client.CreateMockAPIRequest
+	if err := readDefinitions(*file, &reqs); err != nil {
+		return err
+	}
+	created := make([]*client.MockAPI, 0, len(reqs))
+	for _, req := range reqs {
+		if req.Charset == "" {
+			req.Charset = "UTF-8"
+		}
+		mock, err := c.client.CreateMock(ctx, req)
+		if err != nil {
+			if len(created) > 0 {
+				c.printMocks(created)
+			}
+			return fmt.Errorf("mock %q: %w", req.Name, err)
+		}
+		created = append(created, mock)
+	}
+	return c.printMocks(created)
+}
+
+// update replaces a mock with the definition in a file, or patches single
+// fields
+func (c *ctl) update(ctx context.Context, args []string) error {
+	fs := c.flagSet("update")
+	file := fs.String("f", "", "YAML or JSON file holding the new definition (- reads stdin)")
+	args, err := c.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("expected a mock")
+	}
+	if (*file == "") == (len(args) == 1) {
+		return fmt.Errorf("expected either -f or field=value arguments")
+	}
+
+	current, err := c.resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	var mock *client.MockAPI
+	if *file != "" {
+		var defs []*client.MockAPI
+		if err := readDefinitions(*file, &defs); err != nil {
+			return err
+		}
+		if len(defs) != 1 {
+			return fmt.Errorf("%s holds %d mocks, expected one", *file, len(defs))
+		}
+		defs[0].ID = current.ID
+		mock, err = c.client.ReplaceMock(ctx, current.ID, defs[0])
+	} else {
+		patch := make(map[string]interface{})
+		for _, arg := range args[1:] {
+			field, value, found := strings.Cut(arg, "=")
+			if !found {
+				return fmt.Errorf("invalid change: %s (expected field=value or field:=json)", arg)
+			}
+			if raw, isJSON := strings.CutSuffix(field, ":"); isJSON {
+				if !json.Valid([]byte(value)) {
+					return fmt.Errorf("invalid JSON for %s: %s", raw, value)
+				}
+				patch[raw] = json.RawMessage(value)
+				continue
+			}
+			patch[field] = patchValue(value)
+		}
+		mock, err = c.client.PatchMock(ctx, current.ID, patch)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printMocks([]*client.MockAPI{mock})
+}
+
+// delete deletes mocks
+func (c *ctl) delete(ctx context.Context, args []string) error {
+	args, err := c.parse(c.flagSet("delete"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one mock")
+	}
+
+	for _, ref := range args {
+		mock, err := c.resolve(ctx, ref)
+		if err != nil {
+			return err
+		}
+		if err := c.client.DeleteMock(ctx, mock.ID); err != nil {
+			return fmt.Errorf("mock %q: %w", mock.Name, err)
+		}
+		if c.output == "table" {
+			fmt.Fprintf(c.out, "Deleted %s (%s)\n", mock.Name, mock.ID)
+		}
+	}
+	return nil
+}
+
+// start starts mocks
+func (c *ctl) start(ctx context.Context, args []string) error {
+	return c.changeStatus(ctx, "start", args)
+}
+
+// stop stops mocks
+func (c *ctl) stop(ctx context.Context, args []string) error {
+	return c.changeStatus(ctx, "stop", args)
+}
+
+// changeStatus starts or stops mocks
+func (c *ctl) changeStatus(ctx context.Context, name string, args []string) error {
+	args, err := c.parse(c.flagSet(name), args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one mock")
+	}
+	change := c.client.StartMock
+	if name == "stop" {
+		change = c.client.StopMock
+	}
+
+	changed := make([]*client.MockAPI, 0, len(args))
+	for _, ref := range args {
+		mock, err := c.resolve(ctx, ref)
+		if err != nil {
+			return err
+		}
+		if mock, err = change(ctx, mock.ID); err != nil {
+			if len(changed) > 0 {
+				c.printMocks(changed)
+			}
+			return fmt.Errorf("mock %q: %w", ref, err)
+		}
+		changed = append(changed, mock)
+	}
+	return c.printMocks(changed)
+}
+
+// files lists a directory served by an FTP/SFTP mock
+func (c *ctl) files(ctx context.Context, args []string) error {
+	args, err := c.parse(c.flagSet("files"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected a mock and an optional directory")
+	}
+
+	mock, err := c.resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	dir := ""
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	list, err := c.client.ListFiles(ctx, mock.ID, dir)
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(list)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tMODIFIED")
+	for _, file := range list.Files {
+		name := file.Name
+		if file.IsDir {
+			name += "/"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", name, file.Size, file.ModTime)
+	}
+	return w.Flush()
+}
+
+// upload uploads a local file to an FTP/SFTP mock
+func (c *ctl) upload(ctx context.Context, args []string) error {
+	args, err := c.parse(c.flagSet("upload"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("expected a mock, a file and an optional directory")
+	}
+
+	mock, err := c.resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	dir := ""
+	if len(args) == 3 {
+		dir = args[2]
+	}
+
+	uploaded, err := c.client.UploadFile(ctx, mock.ID, dir, filepath.Base(args[1]), file)
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(uploaded)
+	}
+	fmt.Fprintf(c.out, "Uploaded %s (%d bytes)\n", uploaded.Path, uploaded.Size)
+	return nil
+}
+
+// download downloads a file of an FTP/SFTP mock
+func (c *ctl) download(ctx context.Context, args []string) error {
+	args, err := c.parse(c.flagSet("download"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("expected a mock, a path and an optional local file")
+	}
+
+	mock, err := c.resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	target := path.Base(args[1])
+	if len(args) == 3 {
+		target = args[2]
+	}
+	if target == "-" {
+		_, err := c.client.DownloadFile(ctx, mock.ID, args[1], c.out)
+		return err
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	size, err := c.client.DownloadFile(ctx, mock.ID, args[1], file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return err
+	}
+	if c.output == "table" {
+		fmt.Fprintf(c.out, "Downloaded %s to %s (%d bytes)\n", args[1], target, size)
+	}
+	return nil
+}
+
+// resolve finds a mock by ID, or else by its unique name
+func (c *ctl) resolve(ctx context.Context, ref string) (*client.MockAPI, error) {
+	mock, err := c.client.GetMock(ctx, ref)
+	if !errors.Is(err, client.ErrNotFound) {
+		return mock, err
+	}
+
+	mocks, _, err := c.client.ListMocks(ctx, client.ListOptions{Search: ref})
+	if err != nil {
+		return nil, err
+	}
+	var matches []*client.MockAPI
+	for _, mock := range mocks {
+		if mock.Name == ref {
+			matches = append(matches, mock)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("mock %q not found", ref)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%d mocks are named %q, use the ID instead", len(matches), ref)
+}
+
+// printMocks prints mocks as a table or JSON
+func (c *ctl) printMocks(mocks []*client.MockAPI) error {
+	if c.output == "json" {
+		return c.printJSON(mocks)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPROTOCOL\tPORT\tROUTE\tSTATUS\tWORKSPACE")
+	for _, mock := range mocks {
+		port := strconv.Itoa(mock.Port)
+		if mock.UnixSocket != "" {
+			port = mock.UnixSocket
+		}
+		route := strings.TrimSpace(mock.Method + " " + mock.Path)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			mock.ID, mock.Name, mock.Protocol, port, dash(route), mock.Status, dash(mock.Workspace))
+	}
+	return w.Flush()
+}
+
+// printJSON prints a value as indented JSON
+func (c *ctl) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printCtlError reports a failed command, listing invalid fields one per
+// line
+func printCtlError(command string, err error) {
+	fmt.Fprintf(os.Stderr, "ctl %s: %v\n", command, err)
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		for _, field := range apiErr.Fields {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", field.Field, field.Message)
+		}
+	}
+}
+
+// readDefinitions decodes a YAML or JSON file holding a mock, a list of
+// mocks or a config with a mocks key into defs, a pointer to a slice.
+// Fields are matched by their JSON names, which the config uses as well.
+func readDefinitions(file string, defs interface{}) error {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	if config, ok := doc.(map[string]interface{}); ok {
+		if mocks, exists := config["mocks"]; exists {
+			doc = mocks
+		}
+	}
+	if _, ok := doc.([]interface{}); !ok {
+		doc = []interface{}{doc}
+	}
+
+	// Round trip through JSON, as the request types only have JSON tags
+	converted, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	if err := json.Unmarshal(converted, defs); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	return nil
+}
+
+// patchValue converts the value of a field=value argument: numbers,
+// booleans and null are decoded, anything else is a string
+func patchValue(value string) interface{} {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+		switch decoded.(type) {
+		case float64, bool, nil:
+			return decoded
+		}
+	}
+	return value
+}
+
+// envOr returns an environment variable, or fallback if it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// dash returns "-" for empty table cells
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
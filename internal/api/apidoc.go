@@ -0,0 +1,493 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// Responses built with gin.H, described for the API document
+
+type apiError struct {
+	Error   string                  `json:"error"`
+	Fields  []validation.FieldError `json:"fields,omitempty"`  // Invalid fields of a mock definition
+	Deleted int                     `json:"deleted,omitempty"` // Mocks deleted before deleting a workspace failed
+}
+
+type message struct {
+	Message string `json:"message"`
+}
+
+type validationResult struct {
+	Valid  bool                    `json:"valid"`
+	Error  string                  `json:"error,omitempty"`
+	Fields []validation.FieldError `json:"fields,omitempty"`
+}
+
+type workspaceDeleted struct {
+	Message string `json:"message"`
+	Deleted int    `json:"deleted"`
+}
+
+type apiStatus struct {
+	Config     string               `json:"config"`
+	Mocks      int                  `json:"mocks"`
+	LastReload *server.ReloadStatus `json:"last_reload"`
+	Warnings   []string             `json:"warnings"`
+}
+
+type currentUserInfo struct {
+	Name          string   `json:"name"`
+	Method        string   `json:"method,omitempty"`
+	Role          string   `json:"role,omitempty"`
+	Teams         []string `json:"teams,omitempty"`
+	Authenticated bool     `json:"authenticated"`
+}
+
+type portRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+type portList struct {
+	Ports      []server.PortUsage `json:"ports"`
+	AutoAssign portRange          `json:"auto_assign"`
+}
+
+type importResult struct {
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Mocks   []*models.MockAPI `json:"mocks"`
+}
+
+type fileList struct {
+	Files       []FileInfo `json:"files"`
+	CurrentPath string     `json:"current_path"`
+	RootDir     string     `json:"root_dir"`
+}
+
+type fileUploaded struct {
+	Message  string `json:"message"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Path     string `json:"path"`
+}
+
+type fileDeleted struct {
+	Message string `json:"message"`
+	Path    string `json:"path"`
+}
+
+// Request and response bodies that are not JSON
+const (
+	bodyDocument = "document" // Raw body or "file" form field
+	bodyUpload   = "upload"   // Multipart form with "file" and "path"
+	bodyFile     = "file"     // File download
+	bodyExport   = "export"   // YAML, JSON or zip bundle
+	bodyOpenAPI  = "openapi"  // This document
+)
+
+// param is a query parameter
+type param struct {
+	name        string
+	typ         string // string (default), integer or boolean
+	description string
+	array       bool
+	required    bool
+	enum        []string
+}
+
+// operation documents a route of the management API
+type operation struct {
+	method   string
+	path     string // As registered with gin
+	tag      string
+	summary  string
+	query    []param
+	request  interface{} // JSON body type, or one of the body constants
+	status   int         // Success status, 200 if zero
+	response interface{} // JSON body type, or one of the body constants
+}
+
+// Query parameters shared by several operations
+var (
+	limitParam     = param{name: "limit", typ: "integer", description: "Return at most this many entries"}
+	workspaceParam = param{name: "workspace", description: "Put the mocks into this workspace"}
+	formatParam    = param{name: "format", description: "Bundle format", enum: []string{"yaml", "json", "zip"}}
+)
+
+// apiOperations documents every route below /api. The tests fail if a
+// route is missing here, or an entry has no route.
+var apiOperations = []operation{
+	{method: "GET", path: "/api/openapi.json", tag: "meta", summary: "This document", query: []param{
+		{name: "format", description: "Document format", enum: []string{"json", "yaml"}},
+	}, response: bodyOpenAPI},
+	{method: "GET", path: "/api/auth/whoami", tag: "meta", summary: "The authenticated caller", response: currentUserInfo{}},
+
+	{method: "POST", path: "/api/mocks", tag: "mocks", summary: "Create and start a mock; port 0 picks a free port", request: models.CreateMockAPIRequest{}, status: http.StatusCreated, response: models.MockAPI{}},
+	{method: "POST", path: "/api/mocks/validate", tag: "mocks", summary: "Check a mock definition without creating it", request: models.MockAPI{}, response: validationResult{}},
+	{method: "GET", path: "/api/mocks", tag: "mocks", summary: "List mocks; the number of matches is returned in X-Total-Count", query: []param{
+		{name: "workspace", description: "Only mocks of this workspace"},
+		{name: "protocol", description: "Only mocks of this protocol", enum: []string{"http", "https", "tcp", "ftp", "sftp"}},
+		{name: "status", description: "Only running or stopped mocks", enum: []string{"running", "stopped"}},
+		{name: "q", description: "Only mocks whose name, path or content contain this text"},
+		{name: "tag", description: "Only mocks with all of these tags", array: true},
+		{name: "label", description: "Only mocks with all of these key=value labels", array: true},
+		{name: "port", description: "Only mocks on this port or port range, e.g. 9000-9099"},
+		{name: "sort", description: "name, port, protocol, status or workspace; prefix - to reverse"},
+		{name: "offset", typ: "integer", description: "Skip this many matches"},
+		limitParam,
+	}, response: []*models.MockAPI{}},
+	{method: "GET", path: "/api/mocks/:id", tag: "mocks", summary: "Get a mock", response: models.MockAPI{}},
+	{method: "PUT", path: "/api/mocks/:id", tag: "mocks", summary: "Replace the definition of a mock", request: models.MockAPI{}, response: models.MockAPI{}},
+	{method: "PATCH", path: "/api/mocks/:id", tag: "mocks", summary: "Change a mock with a JSON merge patch (RFC 7396)", request: map[string]interface{}{}, response: models.MockAPI{}},
+	{method: "DELETE", path: "/api/mocks/:id", tag: "mocks", summary: "Stop and delete a mock", response: message{}},
+	{method: "POST", path: "/api/mocks/:id/start", tag: "mocks", summary: "Start a stopped mock", response: models.MockAPI{}},
+	{method: "POST", path: "/api/mocks/:id/stop", tag: "mocks", summary: "Stop a mock without deleting it", response: models.MockAPI{}},
+
+	{method: "GET", path: "/api/workspaces", tag: "workspaces", summary: "List workspaces", response: []server.Workspace{}},
+	{method: "POST", path: "/api/workspaces/:name/start", tag: "workspaces", summary: "Start all mocks of a workspace", response: []*models.MockAPI{}},
+	{method: "POST", path: "/api/workspaces/:name/stop", tag: "workspaces", summary: "Stop all mocks of a workspace", response: []*models.MockAPI{}},
+	{method: "DELETE", path: "/api/workspaces/:name", tag: "workspaces", summary: "Delete all mocks of a workspace", response: workspaceDeleted{}},
+	{method: "GET", path: "/api/workspaces/:name/export", tag: "workspaces", summary: "Export the mocks of a workspace", query: []param{formatParam}, response: bodyExport},
+
+	{method: "GET", path: "/api/mocks/:id/violations", tag: "validation", summary: "Requests rejected by OpenAPI contract validation", response: []server.Violation{}},
+	{method: "DELETE", path: "/api/mocks/:id/violations", tag: "validation", summary: "Forget the recorded violations", response: message{}},
+
+	{method: "GET", path: "/api/revisions", tag: "history", summary: "Changes of all mocks, newest first", query: []param{
+		{name: "mock_id", description: "Only changes of this mock"},
+		limitParam,
+	}, response: []*models.Revision{}},
+	{method: "GET", path: "/api/mocks/:id/revisions", tag: "history", summary: "Changes of a mock, newest first", query: []param{limitParam}, response: []*models.Revision{}},
+	{method: "POST", path: "/api/revisions/:rev/rollback", tag: "history", summary: "Restore a mock to the state recorded by a revision", response: models.MockAPI{}},
+
+	{method: "GET", path: "/api/status", tag: "config", summary: "Config location, storage warnings and the last reload", response: apiStatus{}},
+	{method: "POST", path: "/api/reload", tag: "config", summary: "Re-read the config and apply the changes", response: server.ReloadStatus{}},
+
+	{method: "GET", path: "/api/audit", tag: "audit", summary: "Query the audit log, newest first; 404 if auditing is disabled", query: []param{
+		{name: "since", description: "Only entries at or after this RFC 3339 time"},
+		{name: "until", description: "Only entries before this RFC 3339 time"},
+		{name: "actor", description: "Only entries of this user"},
+		{name: "action", description: "Only entries of this action, e.g. mock.update"},
+		{name: "mock_id", description: "Only entries of this mock"},
+		limitParam,
+	}, response: []*audit.Entry{}},
+
+	{method: "GET", path: "/api/ports", tag: "config", summary: "Ports in use and the auto-assign range", response: portList{}},
+
+	{method: "POST", path: "/api/import/openapi", tag: "import", summary: "Create an HTTP mock per operation of an OpenAPI 3 or Swagger 2 document", query: []param{
+		{name: "port", typ: "integer", description: "Port of the mocks", required: true},
+		{name: "protocol", description: "Protocol of the mocks", enum: []string{"http", "https"}},
+		{name: "cert_file", description: "HTTPS certificate file"},
+		{name: "key_file", description: "HTTPS private key file"},
+		{name: "name_prefix", description: "Prepended to every mock name"},
+		{name: "validate", typ: "boolean", description: "Validate requests against the document"},
+		workspaceParam,
+	}, request: bodyDocument, status: http.StatusCreated, response: importResult{}},
+	{method: "GET", path: "/api/export", tag: "import", summary: "Export mocks as a bundle", query: []param{
+		formatParam,
+		{name: "ids", description: "Comma separated IDs of the mocks to export"},
+		{name: "workspace", description: "Export the mocks of this workspace"},
+	}, response: bodyExport},
+	{method: "POST", path: "/api/import", tag: "import", summary: "Import a gomoco bundle, a Postman collection or a HAR file", query: []param{
+		{name: "format", description: "Document format", enum: []string{"bundle", "postman", "har"}},
+		{name: "port", typ: "integer", description: "Port of mocks generated from Postman and HAR files"},
+		{name: "port_offset", typ: "integer", description: "Added to the ports of bundled mocks"},
+		{name: "port_map", description: "Bundled ports to new ports, e.g. 9090:19090,9091:19091"},
+		workspaceParam,
+	}, request: bodyDocument, status: http.StatusCreated, response: importResult{}},
+
+	{method: "GET", path: "/api/mocks/:id/files", tag: "files", summary: "List a directory of an FTP/SFTP mock", query: []param{
+		{name: "path", description: "Directory relative to the root directory"},
+	}, response: fileList{}},
+	{method: "GET", path: "/api/mocks/:id/files/*filepath", tag: "files", summary: "Download a file of an FTP/SFTP mock", response: bodyFile},
+	{method: "POST", path: "/api/mocks/:id/files", tag: "files", summary: "Upload a file (up to 100MB) to an FTP/SFTP mock", request: bodyUpload, response: fileUploaded{}},
+	{method: "DELETE", path: "/api/mocks/:id/files/*filepath", tag: "files", summary: "Delete a file or directory of an FTP/SFTP mock", response: fileDeleted{}},
+}
+
+// pathParams describes the path parameters of the routes
+var pathParams = map[string]param{
+	"id":       {name: "id", description: "Mock ID"},
+	"name":     {name: "name", description: "Workspace name"},
+	"rev":      {name: "rev", typ: "integer", description: "Revision ID"},
+	"filepath": {name: "filepath", description: "File path relative to the root directory"},
+}
+
+// buildAPIDoc generates the OpenAPI 3 document of the management API
+func buildAPIDoc(version string) ([]byte, error) {
+	schemas := newSchemaSet(map[reflect.Type]string{
+		reflect.TypeOf(audit.Entry{}):      "AuditEntry",
+		reflect.TypeOf(validationResult{}): "ValidationResult",
+		reflect.TypeOf(apiError{}):         "Error",
+		reflect.TypeOf(apiStatus{}):        "Status",
+		reflect.TypeOf(currentUserInfo{}):  "User",
+	})
+	errorRef := map[string]interface{}{
+		"description": "Error",
+		"content":     jsonContent(schemas.ref(apiError{})),
+	}
+
+	paths := make(map[string]interface{})
+	for _, op := range apiOperations {
+		path, params := openAPIPath(op.path)
+		for _, query := range op.query {
+			params = append(params, parameter(query, "query"))
+		}
+		if op.response == bodyExport || (op.response != nil && mentionsMock(reflect.TypeOf(op.response))) {
+			params = append(params, parameter(param{
+				name:        "reveal",
+				typ:         "boolean",
+				description: "Return credentials in clear text (admins, or local callers without authentication)",
+			}, "query"))
+		}
+
+		status := op.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		operation := map[string]interface{}{
+			"tags":        []string{op.tag},
+			"summary":     op.summary,
+			"operationId": operationID(op),
+			"responses": map[string]interface{}{
+				fmt.Sprint(status): response(schemas, op.response),
+				"default":          errorRef,
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.request != nil {
+			operation["requestBody"] = requestBody(schemas, op.request)
+		}
+
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(op.method)] = operation
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "gomoco management API",
+			"version":     version,
+			"description": "Create, change and run HTTP, HTTPS, TCP, FTP and SFTP mocks. Errors are returned as {\"error\": \"...\"}.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearer":   map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"apiToken": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Token"},
+				"basic":    map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+		// Credentials are only needed if authentication is enabled
+		"security": []interface{}{
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{"apiToken": []string{}},
+			map[string]interface{}{"basic": []string{}},
+			map[string]interface{}{},
+		},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPIPath converts a gin path to an OpenAPI path and its parameters
+func openAPIPath(path string) (string, []interface{}) {
+	var params []interface{}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+		p, exists := pathParams[name]
+		if !exists {
+			p = param{name: name}
+		}
+		p.required = true
+		params = append(params, parameter(p, "path"))
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// parameter returns an OpenAPI parameter object
+func parameter(p param, in string) map[string]interface{} {
+	typ := p.typ
+	if typ == "" {
+		typ = "string"
+	}
+	schema := map[string]interface{}{"type": typ}
+	if len(p.enum) > 0 {
+		schema["enum"] = p.enum
+	}
+	if p.array {
+		schema = map[string]interface{}{"type": "array", "items": schema}
+	}
+
+	result := map[string]interface{}{
+		"name":   p.name,
+		"in":     in,
+		"schema": schema,
+	}
+	if p.description != "" {
+		result["description"] = p.description
+	}
+	if p.required {
+		result["required"] = true
+	}
+	return result
+}
+
+// requestBody returns the request body object of an operation
+func requestBody(schemas *schemaSet, body interface{}) map[string]interface{} {
+	binary := map[string]interface{}{"type": "string", "format": "binary"}
+	switch body {
+	case bodyDocument:
+		return map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/octet-stream": map[string]interface{}{"schema": binary},
+				"multipart/form-data": map[string]interface{}{"schema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"file": binary},
+					"required":   []string{"file"},
+				}},
+			},
+		}
+	case bodyUpload:
+		return map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"multipart/form-data": map[string]interface{}{"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"file": binary,
+						"path": map[string]interface{}{"type": "string", "description": "Directory relative to the root directory"},
+					},
+					"required": []string{"file"},
+				}},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"required": true,
+		"content":  jsonContent(schemas.ref(body)),
+	}
+}
+
+// response returns the success response object of an operation
+func response(schemas *schemaSet, body interface{}) map[string]interface{} {
+	binary := map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}
+	switch body {
+	case bodyFile:
+		return map[string]interface{}{
+			"description": "File content",
+			"content":     map[string]interface{}{"application/octet-stream": binary},
+		}
+	case bodyExport:
+		return map[string]interface{}{
+			"description": "Bundle of mock definitions",
+			"content": map[string]interface{}{
+				"application/yaml": binary,
+				"application/json": binary,
+				"application/zip":  binary,
+			},
+		}
+	case bodyOpenAPI:
+		return map[string]interface{}{
+			"description": "OpenAPI 3 document",
+			"content":     jsonContent(map[string]interface{}{"type": "object"}),
+		}
+	}
+	return map[string]interface{}{
+		"description": "OK",
+		"content":     jsonContent(schemas.ref(body)),
+	}
+}
+
+// jsonContent returns a content map with a JSON media type
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// operationID derives an operation ID from the method and path, e.g.
+// postMocksIdStart
+func operationID(op operation) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(op.method))
+	for _, segment := range strings.Split(strings.TrimPrefix(op.path, "/api/"), "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool { return r == '.' || r == '_' }) {
+			id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return id.String()
+}
+
+// mentionsMock reports whether a response type contains mock definitions,
+// whose credentials are redacted unless revealed
+func mentionsMock(t reflect.Type) bool {
+	mockType := reflect.TypeOf(models.MockAPI{})
+	seen := make(map[reflect.Type]bool)
+	var visit func(t reflect.Type) bool
+	visit = func(t reflect.Type) bool {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t == mockType {
+			return true
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return false
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			if visit(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(t)
+}
+
+// apiDoc serves the OpenAPI document of the management API, as YAML with
+// ?format=yaml
+func (s *Server) apiDoc(c *gin.Context) {
+	data, err := buildAPIDoc(s.version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("format") != "yaml" {
+		c.Data(http.StatusOK, "application/json", data)
+		return
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if data, err = yaml.Marshal(doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml", data)
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkAPIDoc returns an error listing the routes below /api that are not
+// documented and the documented operations without a route
+func checkAPIDoc(routes gin.RoutesInfo) error {
+	documented := make(map[string]bool, len(apiOperations))
+	for _, op := range apiOperations {
+		documented[op.method+" "+op.path] = true
+	}
+
+	var problems []string
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		if !documented[key] {
+			problems = append(problems, key+" is not documented")
+		}
+		delete(documented, key)
+	}
+	for key := range documented {
+		problems = append(problems, key+" has no route")
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("API document out of date: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// newTestServer returns an API server over an in-memory manager whose mocks
+// listen on localhost
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	manager := server.NewManager(storage.NewMemoryStorage(), server.Options{BindHost: "127.0.0.1"})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		manager.Shutdown(ctx)
+	})
+	log, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(manager, embed.FS{}, Options{Audit: log, DisableUI: true, Version: "test"})
+}
+
+func TestAPIDocMatchesRoutes(t *testing.T) {
+	s := newTestServer(t)
+	if err := checkAPIDoc(s.router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIDocServed(t *testing.T) {
+	s := newTestServer(t)
+	for _, format := range []string{"json", "yaml"} {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json?format="+format, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("format %s: status %d: %s", format, rec.Code, rec.Body)
+		}
+		if !strings.Contains(rec.Body.String(), "openapi") {
+			t.Errorf("format %s: no openapi version in %.100s", format, rec.Body)
+		}
+	}
+}
+
+// apiCall is a request made by TestResponsesMatchAPIDoc
+type apiCall struct {
+	method      string
+	route       string // As documented, e.g. /api/mocks/:id
+	path        string // Defaults to route
+	body        string
+	contentType string
+	status      int
+}
+
+// TestResponsesMatchAPIDoc calls the JSON endpoints and checks that each
+// response only has the fields, and the types, its documented schema has
+func TestResponsesMatchAPIDoc(t *testing.T) {
+	s := newTestServer(t)
+	data, err := buildAPIDoc("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(c apiCall) interface{} {
+		t.Helper()
+		path := c.path
+		if path == "" {
+			path = c.route
+		}
+		req := httptest.NewRequest(c.method, path, strings.NewReader(c.body))
+		contentType := c.contentType
+		if contentType == "" && c.body != "" {
+			contentType = "application/json"
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Fatalf("%s %s: status %d, want %d: %s", c.method, path, rec.Code, c.status, rec.Body)
+		}
+
+		var body interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: %v", c.method, path, err)
+		}
+		schema := responseSchema(t, doc, c.method, c.route, c.status)
+		for _, problem := range checkSchema(doc, schema, body, "") {
+			t.Errorf("%s %s: %s", c.method, c.route, problem)
+		}
+		return body
+	}
+	field := func(body interface{}, name string) string {
+		t.Helper()
+		value, _ := body.(map[string]interface{})[name].(string)
+		if value == "" {
+			t.Fatalf("response has no %s: %v", name, body)
+		}
+		return value
+	}
+
+	httpMock := `{"name":"users","port":0,"protocol":"http","method":"GET","path":"/users",
+		"content":"[]","status_code":200,"content_type":"application/json","charset":"UTF-8",
+		"workspace":"shop","tags":["smoke"],"labels":{"env":"test"}}`
+	created := call(apiCall{method: "POST", route: "/api/mocks", body: httpMock, status: http.StatusCreated})
+	id := field(created, "id")
+	mockPath := "/api/mocks/" + id
+	port := created.(map[string]interface{})["port"]
+
+	ftpMock := fmt.Sprintf(`{"name":"files","port":0,"protocol":"ftp","charset":"UTF-8",
+		"ftp_user":"user","ftp_pass":"secret","ftp_root_dir":%q}`, t.TempDir())
+	ftpID := field(call(apiCall{method: "POST", route: "/api/mocks", body: ftpMock, status: http.StatusCreated}), "id")
+
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	part, _ := form.CreateFormFile("file", "hello.txt")
+	part.Write([]byte("hello"))
+	form.Close()
+
+	openAPIDoc := `{"openapi":"3.0.0","info":{"title":"t","version":"1"},
+		"paths":{"/pets":{"get":{"responses":{"200":{"description":"ok"}}}}}}`
+	bundle := fmt.Sprintf(`{"version":1,"mocks":[{"name":"bundled","port":%.0f,"protocol":"http",
+		"method":"GET","path":"/bundled","status_code":200,"charset":"UTF-8"}]}`, port)
+
+	calls := []apiCall{
+		{method: "GET", route: "/api/auth/whoami", status: http.StatusOK},
+		{method: "POST", route: "/api/mocks/validate", body: httpMock, status: http.StatusOK},
+		{method: "POST", route: "/api/mocks/validate", body: `{"name":"x","protocol":"gopher"}`, status: http.StatusOK},
+		{method: "POST", route: "/api/mocks", body: `{"name":"bad","port":0,"protocol":"gopher","charset":"UTF-8"}`, status: http.StatusBadRequest},
+		{method: "GET", route: "/api/mocks", path: "/api/mocks?workspace=shop", status: http.StatusOK},
+		{method: "GET", route: "/api/mocks/:id", path: mockPath, status: http.StatusOK},
+		{method: "GET", route: "/api/mocks/:id", path: "/api/mocks/unknown", status: http.StatusNotFound},
+		{method: "PUT", route: "/api/mocks/:id", path: mockPath, body: httpMock, status: http.StatusOK},
+		{method: "PATCH", route: "/api/mocks/:id", path: mockPath, body: `{"content":"[1]"}`, status: http.StatusOK},
+		{method: "POST", route: "/api/mocks/:id/stop", path: mockPath + "/stop", status: http.StatusOK},
+		{method: "POST", route: "/api/mocks/:id/start", path: mockPath + "/start", status: http.StatusOK},
+		{method: "GET", route: "/api/workspaces", status: http.StatusOK},
+		{method: "POST", route: "/api/workspaces/:name/stop", path: "/api/workspaces/shop/stop", status: http.StatusOK},
+		{method: "POST", route: "/api/workspaces/:name/start", path: "/api/workspaces/shop/start", status: http.StatusOK},
+		{method: "GET", route: "/api/mocks/:id/violations", path: mockPath + "/violations", status: http.StatusOK},
+		{method: "DELETE", route: "/api/mocks/:id/violations", path: mockPath + "/violations", status: http.StatusOK},
+		{method: "GET", route: "/api/revisions", status: http.StatusOK},
+		{method: "GET", route: "/api/mocks/:id/revisions", path: mockPath + "/revisions", status: http.StatusOK},
+		{method: "GET", route: "/api/status", status: http.StatusOK},
+		{method: "POST", route: "/api/reload", status: http.StatusOK},
+		{method: "GET", route: "/api/audit", status: http.StatusOK},
+		{method: "GET", route: "/api/ports", status: http.StatusOK},
+		{method: "POST", route: "/api/import/openapi", path: fmt.Sprintf("/api/import/openapi?port=%.0f", port), body: openAPIDoc, status: http.StatusCreated},
+		{method: "POST", route: "/api/import", body: bundle, status: http.StatusCreated},
+		{method: "POST", route: "/api/mocks/:id/files", path: "/api/mocks/" + ftpID + "/files", body: upload.String(), contentType: form.FormDataContentType(), status: http.StatusOK},
+		{method: "GET", route: "/api/mocks/:id/files", path: "/api/mocks/" + ftpID + "/files", status: http.StatusOK},
+		{method: "DELETE", route: "/api/mocks/:id/files/*filepath", path: "/api/mocks/" + ftpID + "/files/hello.txt", status: http.StatusOK},
+	}
+	for _, c := range calls {
+		call(c)
+	}
+
+	// The first revision created the mock; rolling back to it restores the content
+	revs := call(apiCall{method: "GET", route: "/api/mocks/:id/revisions", path: mockPath + "/revisions", status: http.StatusOK})
+	list, _ := revs.([]interface{})
+	if len(list) == 0 {
+		t.Fatal("no revisions recorded")
+	}
+	first := list[len(list)-1].(map[string]interface{})
+	call(apiCall{method: "POST", route: "/api/revisions/:rev/rollback", path: fmt.Sprintf("/api/revisions/%.0f/rollback", first["id"]), status: http.StatusOK})
+
+	call(apiCall{method: "DELETE", route: "/api/mocks/:id", path: mockPath, status: http.StatusOK})
+	call(apiCall{method: "DELETE", route: "/api/workspaces/:name", path: "/api/workspaces/shop", status: http.StatusNotFound})
+	call(apiCall{method: "POST", route: "/api/mocks", body: httpMock, status: http.StatusCreated})
+	call(apiCall{method: "DELETE", route: "/api/workspaces/:name", path: "/api/workspaces/shop", status: http.StatusOK})
+}
+
+// responseSchema returns the documented JSON schema of a response, falling
+// back to the default (error) response
+func responseSchema(t *testing.T, doc map[string]interface{}, method, route string, status int) map[string]interface{} {
+	t.Helper()
+	path, _ := openAPIPath(route)
+	operation, ok := lookup(doc, "paths", path, strings.ToLower(method)).(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s %s is not documented", method, route)
+	}
+	response := lookup(operation, "responses", fmt.Sprint(status))
+	if response == nil {
+		response = lookup(operation, "responses", "default")
+	}
+	schema, ok := lookup(response, "content", "application/json", "schema").(map[string]interface{})
+	if !ok {
+		t.Fatalf("%s %s: no JSON schema for status %d", method, route, status)
+	}
+	return schema
+}
+
+// lookup follows a path of keys through decoded JSON objects
+func lookup(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// checkSchema returns the places where a decoded JSON value does not match
+// a schema of the document: undocumented fields, missing required fields,
+// wrong types and values outside an enum. Null matches every schema, as Go
+// encodes nil slices, maps and pointers as null.
+func checkSchema(doc, schema map[string]interface{}, value interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		resolved, ok := lookup(doc, "components", "schemas", name).(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: unknown schema %s", at, ref)}
+		}
+		schema = resolved
+	}
+	if value == nil {
+		return nil
+	}
+	where := at
+	if where == "" {
+		where = "response"
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T is not an object", where, value)}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for key, field := range object {
+			fieldSchema, ok := properties[key].(map[string]interface{})
+			if !ok {
+				fieldSchema = additional
+			}
+			if fieldSchema == nil {
+				problems = append(problems, fmt.Sprintf("%s.%s is not documented", where, key))
+				continue
+			}
+			problems = append(problems, checkSchema(doc, fieldSchema, field, at+"."+key)...)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, exists := object[name.(string)]; !exists {
+				problems = append(problems, fmt.Sprintf("%s.%s is required", where, name))
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T is not an array", where, value)}
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		for i, item := range items {
+			problems = append(problems, checkSchema(doc, itemSchema, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T is not a string", where, value)}
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, text) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not one of %v", where, text, enum))
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			problems = append(problems, fmt.Sprintf("%s: %v is not an integer", where, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			problems = append(problems, fmt.Sprintf("%s: %T is not a number", where, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: %T is not a boolean", where, value))
+		}
+	}
+	return problems
+}
+
+// containsValue reports whether an enum contains a string
+func containsValue(enum []interface{}, value string) bool {
+	for _, candidate := range enum {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditKey is the context key of the audit entry of a request
+const auditKey = "audit"
+
+// auditEntry starts the audit entry of the current request. It is written
+// with the response status once the handler returns, so failed and denied
+// operations are recorded as well.
+func auditEntry(c *gin.Context, action string) *audit.Entry {
+	entry := &audit.Entry{Action: action}
+	c.Set(auditKey, entry)
+	return entry
+}
+
+// writeAudit writes the audit entry started by the handler, if any
+func (s *Server) writeAudit(c *gin.Context) {
+	start := time.Now().UTC()
+	c.Next()
+
+	value, exists := c.Get(auditKey)
+	if !exists || s.audit == nil {
+		return
+	}
+	entry := value.(*audit.Entry)
+	entry.Time = start
+	entry.Actor = *actorFrom(c)
+	entry.Status = c.Writer.Status()
+	if err := s.audit.Append(entry); err != nil {
+		log.Printf("Warning: Failed to write audit log: %v", err)
+	}
+}
+
+// auditMock records which mock an operation affected
+func auditMock(entry *audit.Entry, mock *models.MockAPI) {
+	if mock == nil {
+		return
+	}
+	entry.MockID = mock.ID
+	entry.MockName = mock.Name
+}
+
+// auditChanges records the fields a change touched, in their stored form
+// and with credentials redacted
+func auditChanges(entry *audit.Entry, before, after *models.MockAPI) {
+	var err error
+	if before != nil {
+		if before, err = storage.AtRest(before); err != nil {
+			return
+		}
+	}
+	if after != nil {
+		if after, err = storage.AtRest(after); err != nil {
+			return
+		}
+	}
+	entry.Changes = redactChanges(models.Diff(before, after))
+}
+
+// describeMock summarizes a mock for audit entries
+func describeMock(mock *models.MockAPI) string {
+	if mock.Method != "" || mock.Path != "" {
+		return fmt.Sprintf("%s mock %q on port %d (%s %s)", mock.Protocol, mock.Name, mock.Port, mock.Method, mock.Path)
+	}
+	return fmt.Sprintf("%s mock %q on port %d", mock.Protocol, mock.Name, mock.Port)
+}
+
+// listAudit queries the audit log. Supported filters are since and until
+// (RFC 3339), actor, action, mock_id and limit.
+func (s *Server) listAudit(c *gin.Context) {
+	if err := s.manager.AuthorizeAdmin(actorFrom(c)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if s.audit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audit logging is disabled"})
+		return
+	}
+
+	filter := audit.Filter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		MockID: c.Query("mock_id"),
+	}
+	var err error
+	if value := c.Query("since"); value != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + value})
+			return
+		}
+	}
+	if value := c.Query("until"); value != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + value})
+			return
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: " + value})
+			return
+		}
+	}
+
+	entries, err := s.audit.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Bahtya/Gomoco/internal/auth"
+	"github.com/Bahtya/Gomoco/internal/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userKey is the context key of the authenticated user
+const userKey = "user"
+
+// authenticate rejects requests without valid credentials, unless
+// authentication is disabled
+func (s *Server) authenticate(c *gin.Context) {
+	if len(s.auth) == 0 {
+		return
+	}
+
+	user, err := s.auth.Authenticate(c.Request)
+	if err != nil {
+		message := "Authentication required"
+		if err != auth.ErrNoCredentials {
+			message = "Authentication failed: " + err.Error()
+		}
+		c.Header("WWW-Authenticate", s.auth.Challenge())
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+		return
+	}
+	s.roles.Assign(user)
+	c.Set(userKey, user)
+}
+
+// errorStatus returns 403 for errors caused by missing permissions, 404 for
+// unknown mocks, 400 for invalid definitions and fallback for all others
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, server.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, server.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, server.ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return fallback
+}
+
+// currentUser returns the authenticated user, or nil if authentication is disabled
+func currentUser(c *gin.Context) *auth.User {
+	if user, exists := c.Get(userKey); exists {
+		return user.(*auth.User)
+	}
+	return nil
+}
+
+// whoami returns the authenticated user
+func (s *Server) whoami(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusOK, gin.H{"name": "anonymous", "authenticated": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":          user.Name,
+		"method":        user.Method,
+		"role":          user.Role,
+		"teams":         user.Teams,
+		"authenticated": true,
+	})
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/collection"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportMocks exports all mocks, those listed in the "ids" query parameter
+// or those of a workspace, as a YAML/JSON bundle or as a zip archive
+// including the FTP/SFTP file trees
+func (s *Server) exportMocks(c *gin.Context) {
+	format := c.DefaultQuery("format", collection.FormatYAML)
+	workspace := c.Param("name")
+	if workspace == "" {
+		workspace = c.Query("workspace")
+	}
+
+	mocks, err := s.selectMocks(c.Query("ids"), workspace)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	filename := "gomoco-mocks"
+	if workspace != "" {
+		filename = "gomoco-" + workspace
+	}
+	// Export the stored form, so credentials stay encrypted and secrets
+	// stay placeholders. Credentials are only exported when revealed, as
+	// they are plaintext without a master key.
+	for i, mock := range mocks {
+		saved, err := storage.AtRest(mock)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		mocks[i] = redactMock(c, saved)
+	}
+	bundle := collection.NewBundle(mocks)
+
+	switch format {
+	case collection.FormatZip:
+		var buf bytes.Buffer
+		if err := collection.WriteArchive(&buf, bundle); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+		c.Data(http.StatusOK, "application/zip", buf.Bytes())
+	case collection.FormatYAML, collection.FormatJSON:
+		data, err := bundle.Encode(format)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		contentType := "application/yaml"
+		if format == collection.FormatJSON {
+			contentType = "application/json"
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+		c.Data(http.StatusOK, contentType, data)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format: " + format})
+	}
+}
+
+// selectMocks returns the mocks with the given comma separated IDs, the
+// mocks of a workspace, or all mocks if both are empty, ordered by port and
+// name
+func (s *Server) selectMocks(ids, workspace string) ([]*models.MockAPI, error) {
+	var mocks []*models.MockAPI
+	if ids == "" && workspace != "" {
+		mocks = s.manager.ListWorkspace(workspace)
+		if len(mocks) == 0 {
+			return nil, fmt.Errorf("workspace %s not found", workspace)
+		}
+	} else if ids == "" {
+		mocks = s.manager.List()
+	} else {
+		for _, id := range strings.Split(ids, ",") {
+			mock, err := s.manager.Get(strings.TrimSpace(id))
+			if err != nil {
+				return nil, fmt.Errorf("mock API %s not found", id)
+			}
+			mocks = append(mocks, mock)
+		}
+	}
+
+	sort.Slice(mocks, func(i, j int) bool {
+		if mocks[i].Port != mocks[j].Port {
+			return mocks[i].Port < mocks[j].Port
+		}
+		return mocks[i].Name < mocks[j].Name
+	})
+	return mocks, nil
+}
+
+// importCollection imports a gomoco bundle (YAML, JSON or zip archive), a
+// Postman collection or a HAR file. The document is sent as the request
+// body or as the "file" form field.
+func (s *Server) importCollection(c *gin.Context) {
+	entry := auditEntry(c, audit.ActionMockImport)
+	data, err := readSpec(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := collection.ImportOptions{}
+	if value := c.Query("port"); value != "" {
+		if opts.Port, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port: " + value})
+			return
+		}
+	}
+	if value := c.Query("port_offset"); value != "" {
+		if opts.PortOffset, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port offset: " + value})
+			return
+		}
+	}
+	if opts.PortMap, err = collection.ParsePortMap(c.Query("port_map")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var reqs []*models.CreateMockAPIRequest
+	var archive *collection.Archive
+	switch format := c.DefaultQuery("format", "bundle"); format {
+	case "bundle":
+		reqs, archive, err = importBundle(data, opts)
+	case "postman":
+		reqs, err = collection.FromPostman(data, opts)
+	case "har":
+		reqs, err = collection.FromHAR(data, opts)
+	default:
+		err = fmt.Errorf("unsupported format: %s", format)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setWorkspace(reqs, c.Query("workspace"))
+	var extracted []string
+	var extract func(int, *models.MockAPI) error
+	if archive != nil {
+		extract = func(i int, mock *models.MockAPI) error {
+			paths, err := extractFiles(archive, archive.Bundle.Mocks[i].ID, mock)
+			extracted = append(extracted, paths...)
+			return err
+		}
+	}
+	mocks, skipped, err := s.manager.Import(reqs, actorFrom(c), extract)
+	if err != nil {
+		removePaths(extracted)
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), errorResponse(err))
+		return
+	}
+	entry.Summary = fmt.Sprintf("imported %d mocks, skipped %d", len(mocks), skipped)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"created": len(mocks),
+		"skipped": skipped,
+		"mocks":   redactMocks(c, mocks),
+	})
+}
+
+// importBundle converts a bundle into create requests, in bundle order. For
+// archives, the archive is returned as well, so that its files can be
+// extracted once the mocks are created.
+func importBundle(data []byte, opts collection.ImportOptions) ([]*models.CreateMockAPIRequest, *collection.Archive, error) {
+	if !collection.IsArchive(data) {
+		bundle, err := collection.DecodeBundle(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		reqs, err := bundleRequests(bundle, opts)
+		return reqs, nil, err
+	}
+
+	archive, err := collection.ReadArchive(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	reqs, err := bundleRequests(archive.Bundle, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reqs, archive, nil
+}
+
+// extractFiles extracts the bundled files of a mock into the root directory
+// of the mock created from it, and returns the paths it created
+func extractFiles(archive *collection.Archive, bundledID string, mock *models.MockAPI) ([]string, error) {
+	root := mock.FTPRootDir
+	if mock.Protocol == models.ProtocolSFTP {
+		root = mock.SFTPRootDir
+	}
+	if root == "" {
+		return nil, nil
+	}
+	paths, err := archive.ExtractFiles(bundledID, root)
+	if err != nil {
+		return paths, fmt.Errorf("failed to extract files: %v", err)
+	}
+	return paths, nil
+}
+
+// removePaths removes the files and directories of a failed import, newest
+// first, so that directories are empty when they are removed
+func removePaths(paths []string) {
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := os.Remove(paths[i]); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to remove %s: %v", paths[i], err)
+		}
+	}
+}
+
+// bundleRequests converts the mocks of a bundle, which are exported in the
+// at rest form, into create requests. Escaped text is restored, but
+// placeholders are imported literally rather than resolved on this server.
+func bundleRequests(bundle *collection.Bundle, opts collection.ImportOptions) ([]*models.CreateMockAPIRequest, error) {
+	for _, mock := range bundle.Mocks {
+		if mock != nil {
+			storage.UnescapePlaceholders(mock)
+		}
+	}
+	return bundle.Requests(opts)
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Bahtya/Gomoco/internal/audit"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -102,12 +104,16 @@ func (s *Server) downloadFile(c *gin.Context) {
 	if filePath != "" && filePath[0] == '/' {
 		filePath = filePath[1:]
 	}
+	entry := auditEntry(c, audit.ActionFileDownload)
+	entry.MockID = id
+	entry.Path = filePath
 
 	mock, err := s.manager.Get(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Mock API not found"})
 		return
 	}
+	auditMock(entry, mock)
 
 	if mock.Protocol != "ftp" && mock.Protocol != "sftp" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Not an FTP/SFTP mock API"})
@@ -141,22 +147,31 @@ func (s *Server) downloadFile(c *gin.Context) {
 		return
 	}
 
+	entry.Summary = fmt.Sprintf("downloaded %d bytes", info.Size())
 	c.FileAttachment(fullPath, filepath.Base(filePath))
 }
 
 // uploadFile uploads a file to FTP directory
 func (s *Server) uploadFile(c *gin.Context) {
 	id := c.Param("id")
+	entry := auditEntry(c, audit.ActionFileUpload)
+	entry.MockID = id
+
 	mock, err := s.manager.Get(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Mock API not found"})
 		return
 	}
+	auditMock(entry, mock)
 
 	if mock.Protocol != "ftp" && mock.Protocol != "sftp" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Not an FTP/SFTP mock API"})
 		return
 	}
+	if err := s.manager.Authorize(actorFrom(c), mock); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Get root directory based on protocol
 	rootDir := mock.FTPRootDir
@@ -184,6 +199,7 @@ func (s *Server) uploadFile(c *gin.Context) {
 
 	// Construct full path
 	fullPath := filepath.Join(rootDir, uploadPath, file.Filename)
+	entry.Path = filepath.ToSlash(filepath.Join(uploadPath, file.Filename))
 
 	// Security check
 	absRoot, _ := filepath.Abs(rootDir)
@@ -206,6 +222,7 @@ func (s *Server) uploadFile(c *gin.Context) {
 		return
 	}
 
+	entry.Summary = fmt.Sprintf("uploaded %d bytes", file.Size)
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "File uploaded successfully",
 		"filename": file.Filename,
@@ -221,17 +238,25 @@ func (s *Server) deleteFile(c *gin.Context) {
 	if filePath != "" && filePath[0] == '/' {
 		filePath = filePath[1:]
 	}
+	entry := auditEntry(c, audit.ActionFileDelete)
+	entry.MockID = id
+	entry.Path = filePath
 
 	mock, err := s.manager.Get(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Mock API not found"})
 		return
 	}
+	auditMock(entry, mock)
 
 	if mock.Protocol != "ftp" && mock.Protocol != "sftp" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Not an FTP/SFTP mock API"})
 		return
 	}
+	if err := s.manager.Authorize(actorFrom(c), mock); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Get root directory based on protocol
 	rootDir := mock.FTPRootDir
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// actorHeader names the user making a change until authentication is configured
+const actorHeader = "X-Gomoco-User"
+
+// actorFrom identifies who is making a request. The remote address is the
+// peer address, as forwarding headers can be set by anyone.
+func actorFrom(c *gin.Context) *models.Actor {
+	name := c.GetHeader(actorHeader)
+	if user := currentUser(c); user != nil {
+		return &models.Actor{
+			Name:       user.Name,
+			RemoteAddr: c.RemoteIP(),
+			Role:       user.Role,
+			Teams:      user.Teams,
+		}
+	}
+	if name == "" {
+		name = "anonymous"
+	}
+	return &models.Actor{
+		Name:       name,
+		RemoteAddr: c.RemoteIP(),
+	}
+}
+
+// listRevisions lists the change history of the whole config, newest first
+func (s *Server) listRevisions(c *gin.Context) {
+	revs, err := s.manager.Revisions(c.Query("mock_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, redactRevisions(c, limitRevisions(c, revs)))
+}
+
+// listMockRevisions lists the change history of a single mock, newest first
+func (s *Server) listMockRevisions(c *gin.Context) {
+	revs, err := s.manager.Revisions(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, redactRevisions(c, limitRevisions(c, revs)))
+}
+
+// limitRevisions applies the optional "limit" query parameter
+func limitRevisions(c *gin.Context, revs []*models.Revision) []*models.Revision {
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit >= 0 && limit < len(revs) {
+		return revs[:limit]
+	}
+	return revs
+}
+
+// rollback restores a mock to the state recorded by a revision
+func (s *Server) rollback(c *gin.Context) {
+	entry := auditEntry(c, audit.ActionMockRollback)
+	rev, err := strconv.ParseInt(c.Param("rev"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision: " + c.Param("rev")})
+		return
+	}
+
+	mock, err := s.manager.Rollback(rev, actorFrom(c))
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	auditMock(entry, mock)
+	entry.Summary = fmt.Sprintf("rolled back to revision %d", rev)
+
+	c.JSON(http.StatusOK, redactMock(c, mock))
+}
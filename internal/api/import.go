@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importOpenAPI generates HTTP mocks from an OpenAPI 3 / Swagger 2 document.
+// The document is sent as the request body or as the "file" form field.
+func (s *Server) importOpenAPI(c *gin.Context) {
+	entry := auditEntry(c, audit.ActionMockImport)
+	if err := s.manager.Authorize(actorFrom(c), nil); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	port, err := strconv.Atoi(c.Query("port"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'port' is required"})
+		return
+	}
+
+	data, err := readSpec(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := openapi.Parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opts := openapi.ImportOptions{
+		Port:       port,
+		Protocol:   c.DefaultQuery("protocol", "http"),
+		CertFile:   c.Query("cert_file"),
+		KeyFile:    c.Query("key_file"),
+		NamePrefix: c.Query("name_prefix"),
+	}
+
+	// Keep the document so requests can be validated against it
+	if c.Query("validate") == "true" {
+		opts.SpecPath, err = s.manager.SaveSpec(data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	reqs, err := doc.BuildMocks(opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setWorkspace(reqs, c.Query("workspace"))
+	mocks, skipped, err := s.manager.Import(reqs, actorFrom(c), nil)
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), errorResponse(err))
+		return
+	}
+	entry.Summary = fmt.Sprintf("imported %d mocks, skipped %d", len(mocks), skipped)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"created": len(mocks),
+		"skipped": skipped,
+		"mocks":   redactMocks(c, mocks),
+	})
+}
+
+// readSpec reads the uploaded document from a multipart form or the raw body
+func readSpec(c *gin.Context) ([]byte, error) {
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("No file uploaded")
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxFileSize))
+	}
+	return io.ReadAll(io.LimitReader(c.Request.Body, maxFileSize))
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listPorts lists the ports in use and by which mock, and the range free
+// ports are auto-assigned from
+func (s *Server) listPorts(c *gin.Context) {
+	min, max := s.manager.PortRange()
+	c.JSON(http.StatusOK, gin.H{
+		"ports":       s.manager.Ports(),
+		"auto_assign": gin.H{"min": min, "max": max},
+	})
+}
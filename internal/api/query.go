@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseQuery reads the filters of the mock listing:
+//
+//	workspace, protocol, status  exact match
+//	port                         a port or a range such as 9000-9100
+//	tag                          repeatable or comma separated; all must match
+//	label                        key=value, repeatable; all must match
+//	q                            text in the name, path or content
+//	sort                         name, port, protocol, status or workspace; "-" reverses
+//	offset, limit                paging
+func parseQuery(c *gin.Context) (server.Query, error) {
+	query := server.Query{
+		Workspace: c.Query("workspace"),
+		Protocol:  c.Query("protocol"),
+		Status:    c.Query("status"),
+		Search:    c.Query("q"),
+		Sort:      c.Query("sort"),
+	}
+
+	if value := c.Query("port"); value != "" {
+		low, high, found := strings.Cut(value, "-")
+		if !found {
+			high = low
+		}
+		var err1, err2 error
+		query.MinPort, err1 = strconv.Atoi(strings.TrimSpace(low))
+		query.MaxPort, err2 = strconv.Atoi(strings.TrimSpace(high))
+		if err1 != nil || err2 != nil || query.MinPort > query.MaxPort {
+			return query, fmt.Errorf("invalid port range: %s", value)
+		}
+	}
+
+	for _, value := range c.QueryArray("tag") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				query.Tags = append(query.Tags, tag)
+			}
+		}
+	}
+
+	for _, value := range c.QueryArray("label") {
+		key, labelValue, found := strings.Cut(value, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return query, fmt.Errorf("invalid label selector: %s (expected key=value)", value)
+		}
+		if query.Labels == nil {
+			query.Labels = make(map[string]string)
+		}
+		query.Labels[strings.TrimSpace(key)] = labelValue
+	}
+
+	var err error
+	if value := c.Query("offset"); value != "" {
+		if query.Offset, err = strconv.Atoi(value); err != nil {
+			return query, fmt.Errorf("invalid offset: %s", value)
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil {
+			return query, fmt.Errorf("invalid limit: %s", value)
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaSet generates JSON schemas for Go types. Named structs become
+// components referenced by $ref.
+type schemaSet struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+	overrides  map[reflect.Type]string // Component names of types whose own name is ambiguous
+}
+
+func newSchemaSet(overrides map[reflect.Type]string) *schemaSet {
+	return &schemaSet{
+		components: make(map[string]interface{}),
+		names:      make(map[reflect.Type]string),
+		overrides:  overrides,
+	}
+}
+
+// ref returns the schema of a value's type
+func (s *schemaSet) ref(v interface{}) map[string]interface{} {
+	return s.schema(reflect.TypeOf(v))
+}
+
+// schema returns the schema of a type
+func (s *schemaSet) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		return s.component(t)
+	}
+	// interface{} holds any value
+	return map[string]interface{}{}
+}
+
+// component registers a struct type and returns a reference to it
+func (s *schemaSet) component(t reflect.Type) map[string]interface{} {
+	name, exists := s.names[t]
+	if !exists {
+		name = s.overrides[t]
+		if name == "" {
+			runes := []rune(t.Name())
+			runes[0] = unicode.ToUpper(runes[0])
+			name = string(runes)
+		}
+		if _, taken := s.components[name]; taken {
+			panic(fmt.Sprintf("api: schema name %s is used by two types", name))
+		}
+		s.names[t] = name
+		s.components[name] = nil // Placeholder for recursive types
+		s.components[name] = s.object(t)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// object returns the schema of a struct's JSON fields. Request types also
+// get the required fields and limits of their binding tags.
+func (s *schemaSet) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	isRequest := strings.HasSuffix(t.Name(), "Request")
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := s.schema(field.Type)
+		if isRequest {
+			for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+				key, value, _ := strings.Cut(rule, "=")
+				switch key {
+				case "required":
+					required = append(required, name)
+				case "oneof":
+					schema["enum"] = strings.Fields(value)
+				case "min", "max":
+					if limit, err := strconv.Atoi(value); err == nil {
+						schema[map[string]string{"min": "minimum", "max": "maximum"}[key]] = limit
+					}
+				}
+			}
+		}
+		properties[name] = schema
+	}
+
+	object := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkReveal rejects requests for clear text credentials from callers that
+// may not see them: with authentication only admins may, without it only
+// local callers may
+func checkReveal(c *gin.Context) {
+	if c.Query("reveal") != "true" {
+		return
+	}
+	if user := currentUser(c); user != nil {
+		if user.Role != models.RoleAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Only admins may reveal credentials"})
+		}
+		return
+	}
+	// The peer address, as forwarding headers can be set by anyone
+	if ip := net.ParseIP(c.RemoteIP()); ip == nil || !ip.IsLoopback() {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Revealing credentials is only allowed from localhost"})
+		return
+	}
+}
+
+// revealed reports whether the response may contain clear text credentials
+func revealed(c *gin.Context) bool {
+	return c.Query("reveal") == "true"
+}
+
+// redactMock returns a copy of a mock with its credentials replaced,
+// unless the caller asked for them
+func redactMock(c *gin.Context, mock *models.MockAPI) *models.MockAPI {
+	if mock == nil || revealed(c) {
+		return mock
+	}
+
+	redacted := mock.Clone()
+	if redacted.FTPPass != "" {
+		redacted.FTPPass = models.RedactedValue
+	}
+	if redacted.SFTPPass != "" {
+		redacted.SFTPPass = models.RedactedValue
+	}
+	return redacted
+}
+
+// redactMocks redacts a list of mocks
+func redactMocks(c *gin.Context, mocks []*models.MockAPI) []*models.MockAPI {
+	redacted := make([]*models.MockAPI, len(mocks))
+	for i, mock := range mocks {
+		redacted[i] = redactMock(c, mock)
+	}
+	return redacted
+}
+
+// redactRevisions redacts the credentials in both versions and the changes
+// of each revision
+func redactRevisions(c *gin.Context, revs []*models.Revision) []*models.Revision {
+	if revealed(c) {
+		return revs
+	}
+
+	redacted := make([]*models.Revision, len(revs))
+	for i, rev := range revs {
+		copied := *rev
+		copied.Before = redactMock(c, rev.Before)
+		copied.After = redactMock(c, rev.After)
+		copied.Changes = redactChanges(rev.Changes)
+		redacted[i] = &copied
+	}
+	return redacted
+}
+
+// redactChanges replaces the credentials in a list of field changes
+func redactChanges(changes []models.FieldChange) []models.FieldChange {
+	redacted := make([]models.FieldChange, len(changes))
+	for i, change := range changes {
+		if change.Field == "ftp_pass" || change.Field == "sftp_pass" {
+			if change.Before != nil {
+				change.Before = models.RedactedValue
+			}
+			if change.After != nil {
+				change.After = models.RedactedValue
+			}
+		}
+		redacted[i] = change
+	}
+	return redacted
+}
@@ -1,11 +1,20 @@
 package api
 
 import (
+	"context"
 	"embed"
-	"gomoco/internal/models"
-	"gomoco/internal/server"
+	"encoding/json"
+	"fmt"
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/auth"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"io"
 	"io/fs"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -15,26 +24,68 @@ import (
 type Server struct {
 	manager     *server.Manager
 	router      *gin.Engine
+	http        *http.Server
 	staticFiles embed.FS
+	auth        auth.Chain  // empty if authentication is disabled
+	roles       *auth.Roles // nil makes every authenticated user an admin
+	audit       audit.Store // nil if auditing is disabled
+	disableUI   bool
+	version     string // Reported in the OpenAPI document
+}
+
+// Options configures the API server
+type Options struct {
+	// Auth authenticates API and web UI requests; empty disables
+	// authentication
+	Auth auth.Chain
+	// Roles decide what each authenticated user may change
+	Roles *auth.Roles
+	// Audit records every management operation
+	Audit audit.Store
+	// DisableUI serves the management API only, without the web UI
+	DisableUI bool
+	// Version is reported in the OpenAPI document
+	Version string
+	// AllowedOrigins are the origins other web pages may call the API from.
+	// Without them every origin is allowed if authentication is disabled,
+	// and none but the API's own if it is enabled.
+	AllowedOrigins []string
 }
 
 // NewServer creates a new API server
-func NewServer(manager *server.Manager, staticFiles embed.FS) *Server {
+func NewServer(manager *server.Manager, staticFiles embed.FS, opts Options) *Server {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
 
-	// Configure CORS
+	// Configure CORS. Requests from origins that are not allowed are
+	// rejected, so that other web pages cannot use the credentials a
+	// browser keeps for the API.
 	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept"}
+	switch {
+	case len(opts.AllowedOrigins) > 0:
+		config.AllowOrigins = opts.AllowedOrigins
+	case len(opts.Auth) == 0:
+		config.AllowAllOrigins = true
+	default:
+		config.AllowOriginFunc = func(string) bool { return false }
+	}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Token"}
+	config.ExposeHeaders = []string{"X-Total-Count"}
 	router.Use(cors.New(config))
 
 	s := &Server{
 		manager:     manager,
 		router:      router,
+		http:        &http.Server{Handler: router},
 		staticFiles: staticFiles,
+		auth:        opts.Auth,
+		roles:       opts.Roles,
+		audit:       opts.Audit,
+		disableUI:   opts.DisableUI,
+		version:     opts.Version,
 	}
+	router.Use(s.authenticate)
 
 	s.setupRoutes()
 	return s
@@ -43,12 +94,55 @@ func NewServer(manager *server.Manager, staticFiles embed.FS) *Server {
 // setupRoutes sets up API routes
 func (s *Server) setupRoutes() {
 	api := s.router.Group("/api")
+	api.Use(checkReveal, s.writeAudit)
 	{
-		api.POST("/mocks", s.createMock)
+		// OpenAPI document of this API
+		api.GET("/openapi.json", s.apiDoc)
+
+		api.GET("/auth/whoami", s.whoami)
+
+		api.POST("/mocks", requireJSON, s.createMock)
+		api.POST("/mocks/validate", requireJSON, s.validateMock)
 		api.GET("/mocks", s.listMocks)
 		api.GET("/mocks/:id", s.getMock)
-		api.PUT("/mocks/:id", s.updateMock)
+		api.PUT("/mocks/:id", requireJSON, s.replaceMock)
+		api.PATCH("/mocks/:id", requireJSON, s.patchMock)
 		api.DELETE("/mocks/:id", s.deleteMock)
+		api.POST("/mocks/:id/start", s.startMock)
+		api.POST("/mocks/:id/stop", s.stopMock)
+
+		// Workspaces group mocks that are managed together
+		api.GET("/workspaces", s.listWorkspaces)
+		api.POST("/workspaces/:name/start", s.startWorkspace)
+		api.POST("/workspaces/:name/stop", s.stopWorkspace)
+		api.DELETE("/workspaces/:name", s.deleteWorkspace)
+		api.GET("/workspaces/:name/export", s.exportMocks)
+
+		// OpenAPI contract violations
+		api.GET("/mocks/:id/violations", s.listViolations)
+		api.DELETE("/mocks/:id/violations", s.clearViolations)
+
+		// Revision history
+		api.GET("/revisions", s.listRevisions)
+		api.GET("/mocks/:id/revisions", s.listMockRevisions)
+		api.POST("/revisions/:rev/rollback", s.rollback)
+
+		// Config reload
+		api.GET("/status", s.getStatus)
+		api.POST("/reload", s.reload)
+
+		// Audit log of management operations
+		api.GET("/audit", s.listAudit)
+
+		// Ports in use
+		api.GET("/ports", s.listPorts)
+
+		// Import mocks from an OpenAPI/Swagger document
+		api.POST("/import/openapi", s.importOpenAPI)
+
+		// Mock collections (bundles, Postman, HAR)
+		api.GET("/export", s.exportMocks)
+		api.POST("/import", s.importCollection)
 
 		// FTP file management
 		api.GET("/mocks/:id/files", s.listFiles)
@@ -57,6 +151,10 @@ func (s *Server) setupRoutes() {
 		api.DELETE("/mocks/:id/files/*filepath", s.deleteFile)
 	}
 
+	if s.disableUI {
+		return
+	}
+
 	// Serve embedded static files for frontend
 	distFS, err := fs.Sub(s.staticFiles, "web/dist")
 	if err != nil {
@@ -90,27 +188,58 @@ func (s *Server) setupRoutes() {
 	})
 }
 
+// requireJSON rejects request bodies that are not JSON. HTML forms cannot
+// send JSON, and scripts of other origins cannot send it without a CORS
+// preflight, so state changing requests cannot be forged by other pages.
+func requireJSON(c *gin.Context) {
+	contentType := c.ContentType()
+	if contentType == "application/json" || strings.HasSuffix(contentType, "+json") {
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/json"})
+}
+
 // createMock creates a new mock API
 func (s *Server) createMock(c *gin.Context) {
+	entry := auditEntry(c, audit.ActionMockCreate)
+
+	// Definitions are checked by the manager, which reports every invalid field
 	var req models.CreateMockAPIRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	mock, err := s.manager.Create(&req)
+	mock, err := s.manager.Create(&req, actorFrom(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), errorResponse(err))
 		return
 	}
+	auditMock(entry, mock)
+	auditChanges(entry, nil, mock)
+	entry.Summary = "created " + describeMock(mock)
 
-	c.JSON(http.StatusCreated, mock)
+	c.JSON(http.StatusCreated, redactMock(c, mock))
 }
 
-// listMocks lists all mock APIs
+// listMocks lists the mock APIs matching the query parameters. The total
+// number of matches is returned in the X-Total-Count header.
 func (s *Server) listMocks(c *gin.Context) {
-	mocks := s.manager.List()
-	c.JSON(http.StatusOK, mocks)
+	query, err := parseQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mocks, total, err := s.manager.Query(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, redactMocks(c, mocks))
 }
 
 // getMock gets a mock API by ID
@@ -122,39 +251,204 @@ func (s *Server) getMock(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, mock)
+	c.JSON(http.StatusOK, redactMock(c, mock))
 }
 
-// updateMock updates a mock API
-func (s *Server) updateMock(c *gin.Context) {
-	id := c.Param("id")
-	var req models.UpdateMockAPIRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+// replaceMock replaces the whole definition of a mock API (PUT)
+func (s *Server) replaceMock(c *gin.Context) {
+	var def models.MockAPI
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&def); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	mock, err := s.manager.Update(id, &req)
+	s.changeMock(c, func(id string, actor *models.Actor) (*models.MockAPI, error) {
+		return s.manager.Replace(id, &def, actor)
+	})
+}
+
+// patchMock applies a JSON merge patch to a mock API (PATCH)
+func (s *Server) patchMock(c *gin.Context) {
+	patch, err := io.ReadAll(io.LimitReader(c.Request.Body, maxFileSize))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, mock)
+	s.changeMock(c, func(id string, actor *models.Actor) (*models.MockAPI, error) {
+		return s.manager.Patch(id, patch, actor)
+	})
+}
+
+// changeMock runs an update of the mock in the "id" parameter and responds
+// with the new version
+func (s *Server) changeMock(c *gin.Context, change func(id string, actor *models.Actor) (*models.MockAPI, error)) {
+	id := c.Param("id")
+	entry := auditEntry(c, audit.ActionMockUpdate)
+	entry.MockID = id
+
+	var before *models.MockAPI
+	if current, err := s.manager.Get(id); err == nil {
+		before = current.Clone()
+		auditMock(entry, before)
+	}
+
+	mock, err := change(id, actorFrom(c))
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), errorResponse(err))
+		return
+	}
+	auditMock(entry, mock)
+	auditChanges(entry, before, mock)
+	entry.Summary = "updated " + describeMock(mock)
+
+	c.JSON(http.StatusOK, redactMock(c, mock))
 }
 
 // deleteMock deletes a mock API
 func (s *Server) deleteMock(c *gin.Context) {
 	id := c.Param("id")
-	if err := s.manager.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	entry := auditEntry(c, audit.ActionMockDelete)
+	entry.MockID = id
+
+	var before *models.MockAPI
+	if current, err := s.manager.Get(id); err == nil {
+		before = current.Clone()
+		auditMock(entry, before)
+	}
+
+	if err := s.manager.Delete(id, actorFrom(c)); err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
+	if before != nil {
+		auditChanges(entry, before, nil)
+		entry.Summary = "deleted " + describeMock(before)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Mock API deleted successfully"})
 }
 
-// Run starts the API server
+// startMock starts a stopped mock API
+func (s *Server) startMock(c *gin.Context) {
+	id := c.Param("id")
+	entry := auditEntry(c, audit.ActionMockStart)
+	entry.MockID = id
+
+	mock, err := s.manager.Start(id, actorFrom(c))
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	auditMock(entry, mock)
+	entry.Summary = "started " + describeMock(mock)
+
+	c.JSON(http.StatusOK, redactMock(c, mock))
+}
+
+// stopMock stops a running mock API without deleting it
+func (s *Server) stopMock(c *gin.Context) {
+	id := c.Param("id")
+	entry := auditEntry(c, audit.ActionMockStop)
+	entry.MockID = id
+
+	mock, err := s.manager.Stop(id, actorFrom(c))
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	auditMock(entry, mock)
+	entry.Summary = "stopped " + describeMock(mock)
+
+	c.JSON(http.StatusOK, redactMock(c, mock))
+}
+
+// listViolations lists the requests a mock rejected during contract validation
+func (s *Server) listViolations(c *gin.Context) {
+	id := c.Param("id")
+	violations, err := s.manager.Violations(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, violations)
+}
+
+// clearViolations removes the recorded violations of a mock
+func (s *Server) clearViolations(c *gin.Context) {
+	id := c.Param("id")
+	entry := auditEntry(c, audit.ActionViolationsClear)
+	entry.MockID = id
+	if mock, err := s.manager.Get(id); err == nil {
+		auditMock(entry, mock)
+	}
+
+	if err := s.manager.ClearViolations(id, actorFrom(c)); err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, http.StatusNotFound), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Violations cleared successfully"})
+}
+
+// getStatus reports the config location, storage warnings and the outcome
+// of the last reload
+func (s *Server) getStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"config":      s.manager.ConfigPath(),
+		"mocks":       len(s.manager.List()),
+		"last_reload": s.manager.ReloadStatus(),
+		"warnings":    s.manager.StorageWarnings(),
+	})
+}
+
+// reload re-reads the config file(s) and applies the changes
+func (s *Server) reload(c *gin.Context) {
+	entry := auditEntry(c, audit.ActionConfigReload)
+	if err := s.manager.AuthorizeAdmin(actorFrom(c)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := s.manager.Reload()
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "reload": status})
+		return
+	}
+	entry.Summary = fmt.Sprintf("reloaded: %d added, %d removed, %d changed", status.Added, status.Removed, status.Changed)
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Run serves the API on addr until Shutdown is called
 func (s *Server) Run(addr string) error {
-	return s.router.Run(addr)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if err := s.http.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting API requests and waits for the active ones
+// until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// Handler returns the handler serving the API and the web UI, for serving
+// them from another http.Server or an httptest.Server
+func (s *Server) Handler() http.Handler {
+	return s.router
 }
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/auth"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/storage"
+)
+
+const testToken = "test-token-0123456789"
+
+// newAuthServer returns an API server that accepts testToken
+func newAuthServer(t *testing.T, origins ...string) *Server {
+	t.Helper()
+	manager := server.NewManager(storage.NewMemoryStorage(), server.Options{BindHost: "127.0.0.1"})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		manager.Shutdown(ctx)
+	})
+	tokens := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokens, []byte("tester:"+testToken+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tokenAuth, err := auth.LoadTokens(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(manager, embed.FS{}, Options{Auth: auth.Chain{tokenAuth}, DisableUI: true, AllowedOrigins: origins})
+}
+
+func TestForgedRequestsRejected(t *testing.T) {
+	const mock = `{"name":"m","port":0,"protocol":"http","charset":"UTF-8","method":"GET","path":"/"}`
+	send := func(s *Server, origin, contentType string) int {
+		req := httptest.NewRequest(http.MethodPost, "http://gomoco.local/api/mocks", strings.NewReader(mock))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	s := newAuthServer(t)
+	if code := send(s, "https://evil.example", "application/json"); code != http.StatusForbidden {
+		t.Errorf("request of another origin: status %d, want 403", code)
+	}
+	if code := send(s, "", "text/plain"); code != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain body: status %d, want 415", code)
+	}
+	if code := send(s, "http://gomoco.local", "application/json"); code != http.StatusCreated {
+		t.Errorf("request of the API's own origin: status %d, want 201", code)
+	}
+
+	s = newAuthServer(t, "https://portal.example")
+	if code := send(s, "https://portal.example", "application/json"); code != http.StatusCreated {
+		t.Errorf("request of an allowed origin: status %d, want 201", code)
+	}
+	if code := send(s, "https://evil.example", "application/json"); code != http.StatusForbidden {
+		t.Errorf("request of another origin: status %d, want 403", code)
+	}
+}
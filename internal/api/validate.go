@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorResponse returns the JSON body for an error. Invalid definitions
+// also list their field errors, so clients can point at the broken fields.
+func errorResponse(err error) gin.H {
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		return gin.H{"error": err.Error(), "fields": fields}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+// validateMock checks a mock definition without creating or changing
+// anything (dry run). A definition with the ID of an existing mock is
+// checked as a replacement of it. Invalid definitions are a successful
+// check, so the response is 200 with "valid" false and the field errors.
+func (s *Server) validateMock(c *gin.Context) {
+	var def models.MockAPI
+	if err := json.NewDecoder(c.Request.Body).Decode(&def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.manager.Validate(&def); err != nil {
+		if !errors.Is(err, validation.ErrInvalid) {
+			c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		response := errorResponse(err)
+		response["valid"] = false
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listWorkspaces lists the workspaces with their number of mocks
+func (s *Server) listWorkspaces(c *gin.Context) {
+	c.JSON(http.StatusOK, s.manager.Workspaces())
+}
+
+// startWorkspace starts every mock of a workspace
+func (s *Server) startWorkspace(c *gin.Context) {
+	name := c.Param("name")
+	entry := auditEntry(c, audit.ActionWorkspaceStart)
+	entry.Workspace = name
+
+	mocks, err := s.manager.StartWorkspace(name, actorFrom(c))
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, workspaceErrorStatus(mocks)), gin.H{"error": err.Error(), "mocks": redactMocks(c, mocks)})
+		return
+	}
+	entry.Summary = fmt.Sprintf("started %d mocks", len(mocks))
+
+	c.JSON(http.StatusOK, redactMocks(c, mocks))
+}
+
+// stopWorkspace stops every mock of a workspace
+func (s *Server) stopWorkspace(c *gin.Context) {
+	name := c.Param("name")
+	entry := auditEntry(c, audit.ActionWorkspaceStop)
+	entry.Workspace = name
+
+	mocks, err := s.manager.StopWorkspace(name, actorFrom(c))
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, workspaceErrorStatus(mocks)), gin.H{"error": err.Error(), "mocks": redactMocks(c, mocks)})
+		return
+	}
+	entry.Summary = fmt.Sprintf("stopped %d mocks", len(mocks))
+
+	c.JSON(http.StatusOK, redactMocks(c, mocks))
+}
+
+// deleteWorkspace deletes every mock of a workspace
+func (s *Server) deleteWorkspace(c *gin.Context) {
+	name := c.Param("name")
+	entry := auditEntry(c, audit.ActionWorkspaceDelete)
+	entry.Workspace = name
+
+	mocks, err := s.manager.DeleteWorkspace(name, actorFrom(c))
+	if err != nil {
+		entry.Summary = err.Error()
+		c.JSON(errorStatus(err, workspaceErrorStatus(mocks)), gin.H{"error": err.Error(), "deleted": len(mocks)})
+		return
+	}
+	entry.Summary = fmt.Sprintf("deleted %d mocks", len(mocks))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted successfully", "deleted": len(mocks)})
+}
+
+// workspaceErrorStatus returns 404 if a bulk operation found no mocks and
+// 500 if it failed part way
+func workspaceErrorStatus(mocks []*models.MockAPI) int {
+	if mocks == nil {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// setWorkspace moves imported mocks into a workspace, if one is given
+func setWorkspace(reqs []*models.CreateMockAPIRequest, workspace string) {
+	if workspace == "" {
+		return
+	}
+	for _, req := range reqs {
+		req.Workspace = workspace
+	}
+}
@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// Audited actions
+const (
+	ActionMockCreate      = "mock.create"
+	ActionMockUpdate      = "mock.update"
+	ActionMockDelete      = "mock.delete"
+	ActionMockStart       = "mock.start"
+	ActionMockStop        = "mock.stop"
+	ActionMockRollback    = "mock.rollback"
+	ActionMockImport      = "mock.import"
+	ActionViolationsClear = "violations.clear"
+	ActionFileUpload      = "file.upload"
+	ActionFileDownload    = "file.download"
+	ActionFileDelete      = "file.delete"
+	ActionConfigReload    = "config.reload"
+	ActionWorkspaceStart  = "workspace.start"
+	ActionWorkspaceStop   = "workspace.stop"
+	ActionWorkspaceDelete = "workspace.delete"
+)
+
+// maxLine bounds a single entry in the log
+const maxLine = 16 * 1024 * 1024
+
+// Entry records a single management operation
+type Entry struct {
+	ID        int64                `json:"id"`
+	Time      time.Time            `json:"time"`
+	Actor     models.Actor         `json:"actor"`
+	Action    string               `json:"action"`
+	Status    int                  `json:"status"` // HTTP status of the response
+	MockID    string               `json:"mock_id,omitempty"`
+	MockName  string               `json:"mock_name,omitempty"`
+	Workspace string               `json:"workspace,omitempty"`
+	Path      string               `json:"path,omitempty"` // File affected by file operations
+	Summary   string               `json:"summary,omitempty"`
+	Changes   []models.FieldChange `json:"changes,omitempty"` // Fields before and after the change
+}
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	Actor  string
+	Action string
+	MockID string
+	Limit  int
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(entry *Entry) bool {
+	return (f.Since.IsZero() || !entry.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || entry.Time.Before(f.Until)) &&
+		(f.Actor == "" || entry.Actor.Name == f.Actor) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.MockID == "" || entry.MockID == f.MockID)
+}
+
+// Store records entries and queries them. Log keeps them in a file; the
+// SQLite storage keeps them in its database.
+type Store interface {
+	// Append records an entry and assigns its ID
+	Append(entry *Entry) error
+	// Query returns the entries passing the filter, newest first
+	Query(filter Filter) ([]*Entry, error)
+}
+
+// Log is an append-only audit log stored as one JSON entry per line
+type Log struct {
+	mu     sync.Mutex
+	path   string
+	lastID int64
+}
+
+// Open opens or creates the audit log at path
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+
+	l := &Log{path: path}
+	entries, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		l.lastID = entries[len(entries)-1].ID
+	}
+	return l, nil
+}
+
+// Path returns the audit log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append writes an entry to the log and assigns its ID
+func (l *Log) Append(entry *Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.ID = l.lastID + 1
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+
+	l.lastID = entry.ID
+	return nil
+}
+
+// Query returns the entries passing the filter, newest first
+func (l *Log) Query(filter Filter) ([]*Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*Entry{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(list) == filter.Limit {
+			break
+		}
+		if filter.matches(entries[i]) {
+			list = append(list, entries[i])
+		}
+	}
+	return list, nil
+}
+
+// read reads the whole log in append order. A truncated last line, e.g.
+// after a crash, is skipped.
+func (l *Log) read() ([]*Entry, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	return entries, nil
+}
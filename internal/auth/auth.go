@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Authentication methods
+const (
+	MethodToken = "token"
+	MethodBasic = "basic"
+	MethodOIDC  = "oidc"
+)
+
+// ErrNoCredentials is returned by an authenticator when the request carries
+// no credentials it understands, so the next authenticator can be tried
+var ErrNoCredentials = errors.New("no credentials")
+
+// User is an authenticated caller
+type User struct {
+	Name   string   `json:"name"`
+	Method string   `json:"method"`           // token, basic or oidc
+	Groups []string `json:"groups,omitempty"` // Groups claimed by the OIDC provider
+	Role   string   `json:"role"`             // admin, editor or viewer
+	Teams  []string `json:"teams,omitempty"`
+}
+
+// Authenticator identifies the caller of a request
+type Authenticator interface {
+	// Authenticate returns the user the request belongs to. It returns
+	// ErrNoCredentials if the request has no credentials for this method,
+	// and another error if the credentials are invalid.
+	Authenticate(r *http.Request) (*User, error)
+}
+
+// Chain tries several authenticators in order
+type Chain []Authenticator
+
+// Authenticate returns the user of the first authenticator that recognizes
+// the request's credentials
+func (c Chain) Authenticate(r *http.Request) (*User, error) {
+	for _, authenticator := range c {
+		user, err := authenticator.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		return user, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// Challenge returns the WWW-Authenticate header value for failed requests.
+// Browsers only prompt for a password when basic auth is offered.
+func (c Chain) Challenge() string {
+	for _, authenticator := range c {
+		if _, ok := authenticator.(*BasicAuth); ok {
+			return `Basic realm="gomoco", charset="UTF-8"`
+		}
+	}
+	return `Bearer realm="gomoco"`
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against for unknown users, so that probing for user
+// names takes as long as checking a password
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("gomoco"), bcrypt.DefaultCost)
+
+// maxVerified bounds the cache of verified credentials
+const maxVerified = 1024
+
+// BasicAuth checks HTTP basic auth credentials against bcrypt hashes.
+// Verified credentials are cached, since bcrypt is slow by design and the web
+// UI sends them with every request.
+type BasicAuth struct {
+	hashes map[string][]byte // user name -> bcrypt hash
+
+	mu       sync.Mutex
+	verified map[[32]byte]bool // hash of verified name:password pairs
+}
+
+// LoadPasswords reads an htpasswd style file with one "name:bcrypt-hash"
+// pair per line, as written by "htpasswd -nbB"
+func LoadPasswords(path string) (*BasicAuth, error) {
+	pairs, err := readPairs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &BasicAuth{
+		hashes:   make(map[string][]byte, len(pairs)),
+		verified: make(map[[32]byte]bool),
+	}
+	for name, hash := range pairs {
+		if !strings.HasPrefix(hash, "$2") {
+			return nil, fmt.Errorf("password of %s in %s is not a bcrypt hash", name, path)
+		}
+		auth.hashes[name] = []byte(hash)
+	}
+	return auth, nil
+}
+
+// Authenticate checks the basic auth credentials of the request
+func (a *BasicAuth) Authenticate(r *http.Request) (*User, error) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	key := sha256.Sum256([]byte(name + ":" + password))
+	a.mu.Lock()
+	cached := a.verified[key]
+	a.mu.Unlock()
+	if cached {
+		return &User{Name: name, Method: MethodBasic}, nil
+	}
+
+	hash, exists := a.hashes[name]
+	if !exists {
+		hash = dummyHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !exists {
+		return nil, fmt.Errorf("invalid user name or password")
+	}
+
+	a.mu.Lock()
+	if len(a.verified) >= maxVerified {
+		a.verified = make(map[[32]byte]bool)
+	}
+	a.verified[key] = true
+	a.mu.Unlock()
+	return &User{Name: name, Method: MethodBasic}, nil
+}
+
+// HashPassword returns the bcrypt hash of a password for a password file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
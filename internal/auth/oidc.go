@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval limits how often the signing keys are fetched again
+// when a token names an unknown key
+const jwksRefreshInterval = time.Minute
+
+// clockSkew is tolerated when checking token lifetimes
+const clockSkew = time.Minute
+
+// OIDCAuth accepts ID or access tokens issued by an OpenID Connect provider,
+// sent as "Authorization: Bearer <jwt>". Tokens must be signed with RS256.
+type OIDCAuth struct {
+	issuer        string
+	audience      string
+	usernameClaim string
+	client        *http.Client
+
+	mu        sync.Mutex
+	jwksURI   string
+	keys      map[string]*rsa.PublicKey // key ID -> key
+	fetchedAt time.Time
+}
+
+// OIDCOptions configures an OIDC authenticator
+type OIDCOptions struct {
+	Issuer        string // Issuer URL, used for discovery and checked against "iss"
+	Audience      string // Expected "aud", usually the client ID; empty skips the check
+	UsernameClaim string // Claim holding the user name (default sub, which the issuer keeps unique and stable)
+}
+
+// NewOIDC creates an OIDC authenticator and fetches the provider's signing keys
+func NewOIDC(opts OIDCOptions) (*OIDCAuth, error) {
+	a := &OIDCAuth{
+		issuer:        strings.TrimSuffix(opts.Issuer, "/"),
+		audience:      opts.Audience,
+		usernameClaim: opts.UsernameClaim,
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+	if a.usernameClaim == "" {
+		a.usernameClaim = "sub"
+	}
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := a.getJSON(a.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %v", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != a.issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", discovery.Issuer, a.issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery returned no jwks_uri")
+	}
+	a.jwksURI = discovery.JWKSURI
+
+	if err := a.refreshKeys(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate verifies the bearer JWT of the request
+func (a *OIDCAuth) Authenticate(r *http.Request) (*User, error) {
+	token := bearerToken(r)
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+
+	name := claimString(claims, a.usernameClaim)
+	if name == "" {
+		return nil, fmt.Errorf("invalid token: no %s claim", a.usernameClaim)
+	}
+	return &User{Name: name, Method: MethodOIDC, Groups: claimStrings(claims, "groups")}, nil
+}
+
+// verify checks the signature, issuer, audience and lifetime of a JWT and
+// returns its claims
+func (a *OIDCAuth) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("bad signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if iss := claimString(claims, "iss"); strings.TrimSuffix(iss, "/") != a.issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if a.audience != "" && !hasAudience(claims["aud"], a.audience) {
+		return nil, fmt.Errorf("token is not meant for audience %q", a.audience)
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token not valid yet")
+	}
+	return claims, nil
+}
+
+// key returns the signing key with the given ID, fetching the keys again if
+// the provider rotated them
+func (a *OIDCAuth) key(kid string) (*rsa.PublicKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if key := a.lookup(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(a.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := a.fetchKeys(); err != nil {
+		return nil, err
+	}
+	if key := a.lookup(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup finds a key by ID. Tokens without a key ID match a single key.
+func (a *OIDCAuth) lookup(kid string) *rsa.PublicKey {
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key
+		}
+	}
+	return a.keys[kid]
+}
+
+// refreshKeys fetches the signing keys
+func (a *OIDCAuth) refreshKeys() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.fetchKeys()
+}
+
+// fetchKeys fetches the provider's JSON Web Key Set; a.mu must be held
+func (a *OIDCAuth) fetchKeys() error {
+	a.fetchedAt = time.Now()
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := a.getJSON(a.jwksURI, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch OIDC signing keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err1 := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err1 != nil || err2 != nil || len(e) > 4 {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("OIDC provider published no RSA signing keys")
+	}
+	a.keys = keys
+	return nil
+}
+
+// getJSON fetches and decodes a JSON document
+func (a *OIDCAuth) getJSON(url string, v interface{}) error {
+	resp, err := a.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	return nil
+}
+
+// claimString returns a string claim, or "" if it is missing
+func claimString(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// claimStrings returns a claim holding a list of strings
+func claimStrings(claims map[string]interface{}, name string) []string {
+	values, _ := claims[name].([]interface{})
+	var list []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// hasAudience reports whether the "aud" claim, a string or a list of
+// strings, contains the audience
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
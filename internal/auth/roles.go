@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Roles assigns roles and teams to authenticated users, by authentication
+// method and user name or by the groups an OIDC provider puts into its tokens
+type Roles struct {
+	DefaultRole string             `yaml:"default_role"` // Role of users not listed (default viewer)
+	Users       map[string]Binding `yaml:"users"`        // Keyed by method:name, e.g. basic:alice
+	Groups      map[string]Binding `yaml:"groups"`
+}
+
+// Binding grants a role and team memberships
+type Binding struct {
+	Role  string   `yaml:"role"`
+	Teams []string `yaml:"teams"`
+}
+
+// rolePower orders roles, so that the strongest binding of a user wins
+var rolePower = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleAdmin:  3,
+}
+
+// LoadRoles reads a roles file such as
+//
+//	default_role: viewer
+//	users:
+//	  basic:alice: {role: admin}
+//	  oidc:bob: {role: editor, teams: [payments]}
+//	groups:
+//	  crm-devs: {role: editor, teams: [crm]}
+func LoadRoles(path string) (*Roles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roles file: %v", err)
+	}
+
+	var roles Roles
+	if err := yaml.Unmarshal(data, &roles); err != nil {
+		return nil, fmt.Errorf("failed to parse roles file %s: %v", path, err)
+	}
+	if roles.DefaultRole == "" {
+		roles.DefaultRole = models.RoleViewer
+	}
+	if !models.ValidRole(roles.DefaultRole) {
+		return nil, fmt.Errorf("roles file %s: invalid default role %q", path, roles.DefaultRole)
+	}
+	for name, binding := range roles.Users {
+		if method, user, _ := strings.Cut(name, ":"); !validMethod(method) || user == "" {
+			return nil, fmt.Errorf("roles file %s: user %q must be written as method:name with method token, basic or oidc", path, name)
+		}
+		if !models.ValidRole(binding.Role) {
+			return nil, fmt.Errorf("roles file %s: user %s has invalid role %q", path, name, binding.Role)
+		}
+	}
+	for name, binding := range roles.Groups {
+		if !models.ValidRole(binding.Role) {
+			return nil, fmt.Errorf("roles file %s: group %s has invalid role %q", path, name, binding.Role)
+		}
+	}
+	return &roles, nil
+}
+
+// Assign sets the role and teams of a user. Without a roles file every
+// authenticated user is an admin.
+func (r *Roles) Assign(user *User) {
+	if r == nil {
+		user.Role = models.RoleAdmin
+		return
+	}
+
+	user.Role = r.DefaultRole
+	user.Teams = nil
+	bindings := make([]Binding, 0, len(user.Groups)+1)
+	if binding, exists := r.Users[user.Method+":"+user.Name]; exists {
+		bindings = append(bindings, binding)
+	}
+	for _, group := range user.Groups {
+		if binding, exists := r.Groups[group]; exists {
+			bindings = append(bindings, binding)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, binding := range bindings {
+		if rolePower[binding.Role] > rolePower[user.Role] {
+			user.Role = binding.Role
+		}
+		for _, team := range binding.Teams {
+			if !seen[team] {
+				seen[team] = true
+				user.Teams = append(user.Teams, team)
+			}
+		}
+	}
+}
+
+// validMethod reports whether method names an authentication method
+func validMethod(method string) bool {
+	return method == MethodToken || method == MethodBasic || method == MethodOIDC
+}
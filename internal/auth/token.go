@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TokenAuth accepts static API tokens sent as "Authorization: Bearer <token>"
+// or in the X-API-Token header
+type TokenAuth struct {
+	tokens map[[32]byte]string // token hash -> user name
+}
+
+// LoadTokens reads a token file with one "name:token" pair per line. Empty
+// lines and lines starting with # are ignored.
+func LoadTokens(path string) (*TokenAuth, error) {
+	pairs, err := readPairs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &TokenAuth{tokens: make(map[[32]byte]string, len(pairs))}
+	for name, token := range pairs {
+		if len(token) < 16 {
+			return nil, fmt.Errorf("token of %s in %s is too short (at least 16 characters required)", name, path)
+		}
+		auth.tokens[sha256.Sum256([]byte(token))] = name
+	}
+	return auth, nil
+}
+
+// Authenticate looks up the token of the request
+func (a *TokenAuth) Authenticate(r *http.Request) (*User, error) {
+	token := r.Header.Get("X-API-Token")
+	if token == "" {
+		token = bearerToken(r)
+	}
+	// Bearer tokens that look like JWTs belong to OIDC
+	if token == "" || strings.Count(token, ".") == 2 {
+		return nil, ErrNoCredentials
+	}
+
+	// Compare hashes, so lookups take the same time for every token
+	sum := sha256.Sum256([]byte(token))
+	for hash, name := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			return &User{Name: name, Method: MethodToken}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid API token")
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// readPairs reads a file of "name:value" lines
+func readPairs(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	pairs := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(text, ":")
+		if !found || name == "" || value == "" {
+			return nil, fmt.Errorf("%s:%d: expected name:value", path, line)
+		}
+		if _, exists := pairs[name]; exists {
+			return nil, fmt.Errorf("%s:%d: duplicate entry for %s", path, line, name)
+		}
+		pairs[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return pairs, nil
+}
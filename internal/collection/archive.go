@@ -0,0 +1,259 @@
+package collection
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive layout: the bundle itself plus one directory per FTP/SFTP mock
+const (
+	archiveBundle = "bundle.yaml"
+	archiveFiles  = "files/"
+)
+
+// Limits of what is read from an archive, whose entries may decompress to
+// far more than the archive itself
+const (
+	maxBundleSize    = 32 << 20 // Bytes of the bundle
+	maxExtractedSize = 1 << 30  // Bytes of all files extracted from an archive
+)
+
+// IsArchive reports whether data looks like a zip archive
+func IsArchive(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// WriteArchive writes the bundle and the file trees of its FTP/SFTP mocks as
+// a zip archive. Files are stored under files/<mock id>/.
+func WriteArchive(w io.Writer, b *Bundle) error {
+	zw := zip.NewWriter(w)
+
+	data, err := b.Encode(FormatYAML)
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create(archiveBundle)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	for _, mock := range b.Mocks {
+		root := RootDir(mock)
+		if root == "" {
+			continue
+		}
+		if err := addTree(zw, root, archiveFiles+mock.ID+"/"); err != nil {
+			return fmt.Errorf("failed to archive files of %q: %v", mock.Name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// addTree adds every regular file below root to the archive under prefix
+func addTree(zw *zip.Writer, root, prefix string) error {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = prefix + filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	})
+}
+
+// Archive is a bundle read from a zip archive together with its files
+type Archive struct {
+	Bundle    *Bundle
+	files     map[string][]*zip.File // keyed by mock ID
+	extracted int64                  // Bytes extracted so far
+}
+
+// ReadArchive reads a zip archive written by WriteArchive
+func ReadArchive(data []byte) (*Archive, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %v", err)
+	}
+
+	archive := &Archive{files: make(map[string][]*zip.File)}
+	for _, f := range zr.File {
+		if f.Name == archiveBundle {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := io.ReadAll(io.LimitReader(rc, maxBundleSize+1))
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			if len(content) > maxBundleSize {
+				return nil, fmt.Errorf("%s exceeds %d bytes", archiveBundle, maxBundleSize)
+			}
+			if archive.Bundle, err = DecodeBundle(content); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(f.Name, archiveFiles) || f.FileInfo().IsDir() {
+			continue
+		}
+		rest := strings.TrimPrefix(f.Name, archiveFiles)
+		id, _, found := strings.Cut(rest, "/")
+		if !found {
+			continue
+		}
+		archive.files[id] = append(archive.files[id], f)
+	}
+
+	if archive.Bundle == nil {
+		return nil, fmt.Errorf("archive does not contain %s", archiveBundle)
+	}
+	return archive, nil
+}
+
+// ExtractFiles writes the files of a bundled mock into dir. Existing files
+// are never overwritten and symbolic links below dir are not followed. It
+// returns the files and directories it created, in the order they were
+// created, also when it fails, so that the caller can remove them again.
+func (a *Archive) ExtractFiles(mockID, dir string) ([]string, error) {
+	prefix := archiveFiles + mockID + "/"
+	var created []string
+	for _, f := range a.files[mockID] {
+		rel := path.Clean(strings.TrimPrefix(f.Name, prefix))
+		// Reject entries escaping the root directory
+		if rel == "." || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return created, fmt.Errorf("invalid path in archive: %s", f.Name)
+		}
+		if err := checkParents(dir, path.Dir(rel)); err != nil {
+			return created, err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		dirs, err := mkdirAll(filepath.Dir(target))
+		created = append(created, dirs...)
+		if err != nil {
+			return created, err
+		}
+		// O_EXCL also refuses to open a symbolic link at target
+		dst, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if os.IsExist(err) {
+			return created, fmt.Errorf("%s already exists", target)
+		}
+		if err != nil {
+			return created, err
+		}
+		created = append(created, target)
+
+		n, err := extractFile(f, dst, maxExtractedSize-a.extracted)
+		a.extracted += n
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// checkParents returns an error if a directory on the slash separated path
+// rel below dir exists as a symbolic link or as something other than a
+// directory
+func checkParents(dir, rel string) error {
+	current := dir
+	for _, name := range strings.Split(rel, "/") {
+		if name == "." {
+			continue
+		}
+		current = filepath.Join(current, name)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symbolic link", current)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", current)
+		}
+	}
+	return nil
+}
+
+// mkdirAll creates a directory with its missing parents and returns the
+// directories it created, parents first
+func mkdirAll(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); err == nil || filepath.Dir(d) == d {
+			break
+		}
+		missing = append([]string{d}, missing...)
+	}
+	for _, d := range missing {
+		if err := os.Mkdir(d, 0755); err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// extractFile copies a single archive entry to dst, failing once more than
+// limit bytes are written, and returns the bytes written
+func extractFile(f *zip.File, dst io.Writer, limit int64) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	n, err := io.Copy(dst, io.LimitReader(rc, limit+1))
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, fmt.Errorf("files in archive exceed %d bytes", maxExtractedSize)
+	}
+	return n, nil
+}
@@ -0,0 +1,194 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Export formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatZip  = "zip"
+)
+
+// bundleVersion is the version written into exported bundles
+const bundleVersion = 1
+
+// Bundle is a portable set of mock definitions
+type Bundle struct {
+	Version    int               `json:"version" yaml:"version"`
+	ExportedAt time.Time         `json:"exported_at" yaml:"exported_at"`
+	Mocks      []*models.MockAPI `json:"mocks" yaml:"mocks"`
+}
+
+// NewBundle creates a bundle of the given mocks
+func NewBundle(mocks []*models.MockAPI) *Bundle {
+	return &Bundle{
+		Version:    bundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Mocks:      mocks,
+	}
+}
+
+// Encode renders the bundle as YAML or JSON
+func (b *Bundle) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(b)
+	case FormatJSON:
+		return json.MarshalIndent(b, "", "  ")
+	}
+	return nil, fmt.Errorf("unsupported format: %s", format)
+}
+
+// DecodeBundle parses a YAML or JSON bundle
+func DecodeBundle(data []byte) (*Bundle, error) {
+	// JSON is a subset of YAML, but tab indented JSON is not
+	var bundle Bundle
+	var err error
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		err = json.Unmarshal(data, &bundle)
+	} else {
+		err = yaml.Unmarshal(data, &bundle)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %v", err)
+	}
+	if bundle.Version > bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version: %d", bundle.Version)
+	}
+	return &bundle, nil
+}
+
+// ImportOptions controls how imported mocks are placed
+type ImportOptions struct {
+	Port       int         // Port for mocks generated from Postman and HAR files
+	PortOffset int         // Added to every bundle port not listed in PortMap
+	PortMap    map[int]int // Explicit old port to new port mapping
+}
+
+// ParsePortMap parses a mapping such as "9090:19090,9091:19091"
+func ParsePortMap(value string) (map[int]int, error) {
+	portMap := make(map[int]int)
+	if strings.TrimSpace(value) == "" {
+		return portMap, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(pair), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid port mapping %q (expected old:new)", pair)
+		}
+		from, err1 := strconv.Atoi(parts[0])
+		to, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid port mapping %q (expected old:new)", pair)
+		}
+		portMap[from] = to
+	}
+	return portMap, nil
+}
+
+// remap returns the new port for a bundle port
+func (o ImportOptions) remap(port int) int {
+	if to, exists := o.PortMap[port]; exists {
+		return to
+	}
+	return port + o.PortOffset
+}
+
+// Requests converts the bundle into create requests with remapped ports, in
+// the same order as Mocks. IDs are not carried over; the manager assigns new ones.
+func (b *Bundle) Requests(opts ImportOptions) ([]*models.CreateMockAPIRequest, error) {
+	reqs := make([]*models.CreateMockAPIRequest, 0, len(b.Mocks))
+	for _, mock := range b.Mocks {
+		if mock == nil {
+			return nil, fmt.Errorf("bundle contains an empty mock entry")
+		}
+		req := toRequest(mock)
+		req.Port = opts.remap(mock.Port)
+		if req.Port < 1 || req.Port > 65535 {
+			return nil, fmt.Errorf("mock %q: port %d is out of range after remapping", mock.Name, req.Port)
+		}
+
+		// Default root directories are derived from the port, so follow the remapping
+		if req.FTPRootDir == defaultRootDir("ftp_data", mock.Port) {
+			req.FTPRootDir = defaultRootDir("ftp_data", req.Port)
+		}
+		if req.SFTPRootDir == defaultRootDir("sftp_data", mock.Port) {
+			req.SFTPRootDir = defaultRootDir("sftp_data", req.Port)
+		}
+		if mock.Protocol == models.ProtocolFTP && req.FTPRootDir == "" {
+			req.FTPRootDir = defaultRootDir("ftp_data", req.Port)
+		}
+		if mock.Protocol == models.ProtocolSFTP && req.SFTPRootDir == "" {
+			req.SFTPRootDir = defaultRootDir("sftp_data", req.Port)
+		}
+
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// defaultRootDir returns the root directory FTP/SFTP servers use by default
+func defaultRootDir(base string, port int) string {
+	return filepath.Join(base, fmt.Sprintf("port_%d", port))
+}
+
+// toRequest copies the definition of a mock into a create request
+func toRequest(mock *models.MockAPI) *models.CreateMockAPIRequest {
+	charset := mock.Charset
+	if charset == "" {
+		charset = models.CharsetUTF8
+	}
+	return &models.CreateMockAPIRequest{
+		Name:                mock.Name,
+		Port:                mock.Port,
+		Protocol:            mock.Protocol,
+		BindHost:            mock.BindHost,
+		UnixSocket:          mock.UnixSocket,
+		CertFile:            mock.CertFile,
+		KeyFile:             mock.KeyFile,
+		FTPMode:             mock.FTPMode,
+		FTPRootDir:          mock.FTPRootDir,
+		FTPUser:             mock.FTPUser,
+		FTPPass:             mock.FTPPass,
+		FTPPassivePortRange: mock.FTPPassivePortRange,
+		SFTPRootDir:         mock.SFTPRootDir,
+		SFTPUser:            mock.SFTPUser,
+		SFTPPass:            mock.SFTPPass,
+		SFTPHostKey:         mock.SFTPHostKey,
+		SFTPPrivateKey:      mock.SFTPPrivateKey,
+		Content:             mock.Content,
+		Charset:             charset,
+		Path:                mock.Path,
+		Method:              mock.Method,
+		StatusCode:          mock.StatusCode,
+		ContentType:         mock.ContentType,
+		Headers:             mock.Headers,
+		OpenAPISpec:         mock.OpenAPISpec,
+		ValidationStatus:    mock.ValidationStatus,
+		Workspace:           mock.Workspace,
+		Tags:                mock.Tags,
+		Labels:              mock.Labels,
+	}
+}
+
+// RootDir returns the file tree root of an FTP or SFTP mock
+func RootDir(mock *models.MockAPI) string {
+	switch mock.Protocol {
+	case models.ProtocolFTP:
+		return mock.FTPRootDir
+	case models.ProtocolSFTP:
+		return mock.SFTPRootDir
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package collection
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// harFile is the subset of an HTTP Archive used for import
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int         `json:"status"`
+		Headers []harHeader `json:"headers"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// FromHAR converts the entries of a browser HAR file into HTTP mocks. When a
+// method and path were recorded more than once, the first response is kept.
+func FromHAR(data []byte, opts ImportOptions) ([]*models.CreateMockAPIRequest, error) {
+	if err := checkPort(opts.Port); err != nil {
+		return nil, err
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file: %v", err)
+	}
+
+	reqs := make([]*models.CreateMockAPIRequest, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+
+		content := entry.Response.Content.Text
+		if entry.Response.Content.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(content)
+			if err != nil {
+				continue
+			}
+			content = string(decoded)
+		}
+
+		headers := make(map[string]string, len(entry.Response.Headers))
+		for _, h := range entry.Response.Headers {
+			headers[h.Name] = h.Value
+		}
+		contentType, extra := splitHeaders(headers)
+		if contentType == "" {
+			contentType = entry.Response.Content.MimeType
+		}
+
+		method := strings.ToUpper(entry.Request.Method)
+		reqs = append(reqs, &models.CreateMockAPIRequest{
+			Name:        method + " " + path,
+			Port:        opts.Port,
+			Protocol:    models.ProtocolHTTP,
+			Charset:     models.CharsetUTF8,
+			Method:      method,
+			Path:        path,
+			StatusCode:  entry.Response.Status,
+			ContentType: contentType,
+			Headers:     extra,
+			Content:     content,
+		})
+	}
+
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("HAR file contains no entries")
+	}
+	return dedupe(reqs), nil
+}
+
+// hopHeaders are recorded response headers that must not be replayed
+var hopHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Date":              true,
+	"Set-Cookie":        true,
+}
+
+// splitHeaders separates the content type from the headers worth replaying
+func splitHeaders(headers map[string]string) (string, map[string]string) {
+	contentType := ""
+	extra := make(map[string]string)
+	for name, value := range headers {
+		canonical := http.CanonicalHeaderKey(name)
+		switch {
+		case canonical == "Content-Type":
+			contentType = value
+		case hopHeaders[canonical], strings.HasPrefix(name, ":"):
+			// HTTP/2 pseudo headers and connection specific headers
+		default:
+			extra[canonical] = value
+		}
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	return contentType, extra
+}
+
+// dedupe keeps the first mock for each method and path
+func dedupe(reqs []*models.CreateMockAPIRequest) []*models.CreateMockAPIRequest {
+	seen := make(map[string]bool)
+	unique := reqs[:0]
+	for _, req := range reqs {
+		key := req.Method + " " + req.Path
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, req)
+	}
+	return unique
+}
+
+// checkPort validates the port generated HTTP mocks are placed on
+func checkPort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("a port between 1 and 65535 is required")
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// postmanCollection is the subset of a Postman v2.0/v2.1 collection used for import
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item []postmanItem `json:"item"`
+}
+
+// postmanItem is either a folder (with Item) or a request
+type postmanItem struct {
+	Name     string            `json:"name"`
+	Item     []postmanItem     `json:"item"`
+	Request  *postmanRequest   `json:"request"`
+	Response []postmanResponse `json:"response"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	URL    json.RawMessage `json:"url"` // Either a string or an object
+}
+
+type postmanURL struct {
+	Raw  string          `json:"raw"`
+	Path json.RawMessage `json:"path"` // Either a string or a list of segments
+}
+
+type postmanResponse struct {
+	Name   string          `json:"name"`
+	Code   int             `json:"code"`
+	Header []postmanHeader `json:"header"`
+	Body   string          `json:"body"`
+}
+
+type postmanHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// postmanVariable matches {{variable}} placeholders
+var postmanVariable = regexp.MustCompile(`\{\{\s*([^}\s]+)\s*\}\}`)
+
+// FromPostman converts the requests of a Postman collection into HTTP mocks.
+// The first saved example response of each request becomes the mock response.
+func FromPostman(data []byte, opts ImportOptions) ([]*models.CreateMockAPIRequest, error) {
+	if err := checkPort(opts.Port); err != nil {
+		return nil, err
+	}
+
+	var coll postmanCollection
+	if err := json.Unmarshal(data, &coll); err != nil {
+		return nil, fmt.Errorf("failed to parse Postman collection: %v", err)
+	}
+	if !strings.Contains(coll.Info.Schema, "postman") {
+		return nil, fmt.Errorf("not a Postman collection")
+	}
+
+	var reqs []*models.CreateMockAPIRequest
+	var walk func(items []postmanItem, folder string)
+	walk = func(items []postmanItem, folder string) {
+		for _, item := range items {
+			if item.Request == nil {
+				walk(item.Item, folder+item.Name+"/")
+				continue
+			}
+
+			req := &models.CreateMockAPIRequest{
+				Name:     folder + item.Name,
+				Port:     opts.Port,
+				Protocol: models.ProtocolHTTP,
+				Charset:  models.CharsetUTF8,
+				Method:   strings.ToUpper(item.Request.Method),
+				Path:     postmanPath(item.Request.URL),
+			}
+			if len(item.Response) > 0 {
+				resp := item.Response[0]
+				req.StatusCode = resp.Code
+				req.Content = resp.Body
+				headers := make(map[string]string, len(resp.Header))
+				for _, h := range resp.Header {
+					headers[h.Key] = h.Value
+				}
+				req.ContentType, req.Headers = splitHeaders(headers)
+			}
+			reqs = append(reqs, req)
+		}
+	}
+	walk(coll.Item, "")
+
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("collection contains no requests")
+	}
+	return dedupe(reqs), nil
+}
+
+// postmanPath extracts the request path from a Postman URL, turning
+// ":param" and "{{param}}" segments into "{param}" templates
+func postmanPath(raw json.RawMessage) string {
+	var segments []string
+
+	var str string
+	var url postmanURL
+	if err := json.Unmarshal(raw, &str); err == nil {
+		segments = strings.Split(rawURLPath(str), "/")
+	} else if err := json.Unmarshal(raw, &url); err == nil {
+		var list []string
+		if err := json.Unmarshal(url.Path, &list); err == nil {
+			segments = list
+		} else if err := json.Unmarshal(url.Path, &str); err == nil {
+			segments = strings.Split(str, "/")
+		} else {
+			segments = strings.Split(rawURLPath(url.Raw), "/")
+		}
+	}
+
+	parts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, ":") {
+			seg = "{" + seg[1:] + "}"
+		}
+		seg = postmanVariable.ReplaceAllString(seg, "{$1}")
+		parts = append(parts, seg)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// rawURLPath strips the scheme, host and query from a raw URL, which may
+// contain variables and therefore cannot be parsed with net/url
+func rawURLPath(raw string) string {
+	raw, _, _ = strings.Cut(raw, "?")
+	raw, _, _ = strings.Cut(raw, "#")
+	if _, rest, found := strings.Cut(raw, "://"); found {
+		raw = rest
+	}
+	// Drop the host part, which may itself be a {{baseUrl}} variable
+	if i := strings.Index(raw, "/"); i >= 0 {
+		return raw[i:]
+	}
+	return "/"
+}
@@ -0,0 +1,23 @@
+package models
+
+// Roles of authenticated users
+const (
+	RoleAdmin  = "admin"  // may change every mock and the server configuration
+	RoleEditor = "editor" // may create mocks and change the mocks of their teams
+	RoleViewer = "viewer" // may only view mocks
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleEditor || role == RoleViewer
+}
+
+// InTeam reports whether the actor is a member of team
+func (a *Actor) InTeam(team string) bool {
+	for _, t := range a.Teams {
+		if t == team {
+			return true
+		}
+	}
+	return false
+}
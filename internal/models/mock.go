@@ -21,14 +21,21 @@ const (
 	CharsetGBK  = "GBK"
 )
 
+// RedactedValue replaces credentials in API responses. Updates that send it
+// back leave the stored credential unchanged.
+const RedactedValue = "******"
+
 // MockAPI represents a mock API configuration
 type MockAPI struct {
-	ID       string `json:"id" yaml:"id" binding:"required"`
-	Name     string `json:"name" yaml:"name" binding:"required"`
-	Port     int    `json:"port" yaml:"port" binding:"required,min=1,max=65535"`
-	Protocol string `json:"protocol" yaml:"protocol" binding:"required,oneof=http https tcp ftp sftp"`
-	CertFile string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"` // HTTPS certificate file path
-	KeyFile  string `json:"key_file,omitempty" yaml:"key_file,omitempty"`   // HTTPS private key file path
+	ID       string `json:"id" yaml:"id"`
+	Name     string `json:"name" yaml:"name"`
+	Port     int    `json:"port" yaml:"port"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	// Network fields
+	BindHost   string `json:"bind_host,omitempty" yaml:"bind_host,omitempty"`     // Address to listen on, e.g. 127.0.0.1 or ::1 (default all interfaces)
+	UnixSocket string `json:"unix_socket,omitempty" yaml:"unix_socket,omitempty"` // Unix socket HTTP/TCP mocks listen on instead of the port
+	CertFile   string `json:"cert_file,omitempty" yaml:"cert_file,omitempty"`     // HTTPS certificate file path
+	KeyFile    string `json:"key_file,omitempty" yaml:"key_file,omitempty"`       // HTTPS private key file path
 	// FTP specific fields
 	FTPMode             string `json:"ftp_mode,omitempty" yaml:"ftp_mode,omitempty"`                             // active or passive
 	FTPRootDir          string `json:"ftp_root_dir,omitempty" yaml:"ftp_root_dir,omitempty"`                     // FTP root directory
@@ -42,19 +49,41 @@ type MockAPI struct {
 	SFTPHostKey    string `json:"sftp_host_key,omitempty" yaml:"sftp_host_key,omitempty"`       // SFTP host key file path
 	SFTPPrivateKey string `json:"sftp_private_key,omitempty" yaml:"sftp_private_key,omitempty"` // SFTP private key file path (optional)
 	Content        string `json:"content" yaml:"content"`
-	Charset        string `json:"charset" yaml:"charset" binding:"required,oneof=UTF-8 GBK"`
+	Charset        string `json:"charset" yaml:"charset"`
 	Path           string `json:"path,omitempty" yaml:"path,omitempty"`     // Only for HTTP protocol
 	Method         string `json:"method,omitempty" yaml:"method,omitempty"` // Only for HTTP protocol (GET, POST, etc.)
-	Status         string `json:"status" yaml:"-"`                          // running, stopped
+	// HTTP response fields
+	StatusCode  int               `json:"status_code,omitempty" yaml:"status_code,omitempty"`   // Response status code (default 200)
+	ContentType string            `json:"content_type,omitempty" yaml:"content_type,omitempty"` // Response content type (default text/plain)
+	Headers     map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`           // Extra response headers
+	// HTTP contract validation
+	OpenAPISpec      string `json:"openapi_spec,omitempty" yaml:"openapi_spec,omitempty"`           // OpenAPI document requests are validated against
+	ValidationStatus int    `json:"validation_status,omitempty" yaml:"validation_status,omitempty"` // Status code for invalid requests (default 400)
+	Status           string `json:"status" yaml:"-"`                                                // running, stopped
+	Source           string `json:"source,omitempty" yaml:"-"`                                      // Config file the mock is stored in
+	// Workspace groups mocks that are managed together, e.g. "payments-staging"
+	Workspace string `json:"workspace,omitempty" yaml:"workspace,omitempty"`
+	// Tags and labels for searching and filtering
+	Tags   []string          `json:"tags,omitempty" yaml:"tags,omitempty"`     // e.g. ["smoke", "v2"]
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"` // e.g. {"env": "staging"}
+	// Ownership
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"` // User who created the mock
+	Team  string `json:"team,omitempty" yaml:"team,omitempty"`   // Team whose members may change the mock
+	// Placeholders holds the ${...} form of fields that were resolved on load,
+	// keyed by JSON field name, so they are saved back unresolved
+	Placeholders map[string]string `json:"-" yaml:"-"`
 }
 
 // CreateMockAPIRequest represents the request to create a mock API
 type CreateMockAPIRequest struct {
-	Name     string `json:"name" binding:"required"`
-	Port     int    `json:"port" binding:"required,min=1,max=65535"`
-	Protocol string `json:"protocol" binding:"required,oneof=http https tcp ftp sftp"`
-	CertFile string `json:"cert_file,omitempty"` // HTTPS certificate file path
-	KeyFile  string `json:"key_file,omitempty"`  // HTTPS private key file path
+	Name     string `json:"name"`
+	Port     int    `json:"port"` // 0 picks a free port
+	Protocol string `json:"protocol"`
+	// Network fields
+	BindHost   string `json:"bind_host,omitempty"`
+	UnixSocket string `json:"unix_socket,omitempty"`
+	CertFile   string `json:"cert_file,omitempty"` // HTTPS certificate file path
+	KeyFile    string `json:"key_file,omitempty"`  // HTTPS private key file path
 	// FTP specific fields
 	FTPMode             string `json:"ftp_mode,omitempty"`
 	FTPRootDir          string `json:"ftp_root_dir,omitempty"`
@@ -68,30 +97,23 @@ type CreateMockAPIRequest struct {
 	SFTPHostKey    string `json:"sftp_host_key,omitempty"`
 	SFTPPrivateKey string `json:"sftp_private_key,omitempty"`
 	Content        string `json:"content"`
-	Charset        string `json:"charset" binding:"required,oneof=UTF-8 GBK"`
+	Charset        string `json:"charset"`
 	Path           string `json:"path,omitempty"`
 	Method         string `json:"method,omitempty"`
-}
-
-// UpdateMockAPIRequest represents the request to update a mock API
-type UpdateMockAPIRequest struct {
-	Name     string `json:"name,omitempty"`
-	Content  string `json:"content,omitempty"`
-	Charset  string `json:"charset,omitempty"`
-	Path     string `json:"path,omitempty"`
-	Method   string `json:"method,omitempty"`
-	CertFile string `json:"cert_file,omitempty"`
-	KeyFile  string `json:"key_file,omitempty"`
-	// FTP specific fields
-	FTPMode             string `json:"ftp_mode,omitempty"`
-	FTPRootDir          string `json:"ftp_root_dir,omitempty"`
-	FTPUser             string `json:"ftp_user,omitempty"`
-	FTPPass             string `json:"ftp_pass,omitempty"`
-	FTPPassivePortRange string `json:"ftp_passive_port_range,omitempty"`
-	// SFTP specific fields
-	SFTPRootDir    string `json:"sftp_root_dir,omitempty"`
-	SFTPUser       string `json:"sftp_user,omitempty"`
-	SFTPPass       string `json:"sftp_pass,omitempty"`
-	SFTPHostKey    string `json:"sftp_host_key,omitempty"`
-	SFTPPrivateKey string `json:"sftp_private_key,omitempty"`
+	// HTTP response fields
+	StatusCode  int               `json:"status_code,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	// HTTP contract validation
+	OpenAPISpec      string `json:"openapi_spec,omitempty"`
+	ValidationStatus int    `json:"validation_status,omitempty"`
+	// Config file name inside the config directory (directory mode only)
+	Source string `json:"source,omitempty"`
+	// Team the mock belongs to; defaults to the first team of the creator
+	Team string `json:"team,omitempty"`
+	// Workspace the mock is grouped into
+	Workspace string `json:"workspace,omitempty"`
+	// Tags and labels for searching and filtering
+	Tags   []string          `json:"tags,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
 }
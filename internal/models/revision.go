@@ -0,0 +1,115 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Revision actions
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionRollback = "rollback"
+	ActionReload   = "reload"
+)
+
+// Actor identifies who performed a change. Actors without a role, such as
+// the system or callers when authentication is disabled, are not subject to
+// access control.
+type Actor struct {
+	Name       string   `json:"name"`
+	RemoteAddr string   `json:"remote_addr,omitempty"`
+	Role       string   `json:"role,omitempty"`
+	Teams      []string `json:"teams,omitempty"`
+}
+
+// SystemActor is used for changes not made through the API, such as
+// config file reloads
+var SystemActor = &Actor{Name: "system"}
+
+// Revision records a single change to a mock
+type Revision struct {
+	ID       int64         `json:"id"`
+	MockID   string        `json:"mock_id"`
+	MockName string        `json:"mock_name"`
+	Action   string        `json:"action"`
+	Actor    Actor         `json:"actor"`
+	Time     time.Time     `json:"time"`
+	Before   *MockAPI      `json:"before,omitempty"` // nil for create
+	After    *MockAPI      `json:"after,omitempty"`  // nil for delete
+	Changes  []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange describes how a single field changed
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// Clone returns a copy of the mock that shares no mutable state
+func (m *MockAPI) Clone() *MockAPI {
+	if m == nil {
+		return nil
+	}
+	clone := *m
+	if m.Headers != nil {
+		clone.Headers = make(map[string]string, len(m.Headers))
+		for key, value := range m.Headers {
+			clone.Headers[key] = value
+		}
+	}
+	if m.Tags != nil {
+		clone.Tags = append([]string(nil), m.Tags...)
+	}
+	if m.Labels != nil {
+		clone.Labels = make(map[string]string, len(m.Labels))
+		for key, value := range m.Labels {
+			clone.Labels[key] = value
+		}
+	}
+	if m.Placeholders != nil {
+		clone.Placeholders = make(map[string]string, len(m.Placeholders))
+		for key, value := range m.Placeholders {
+			clone.Placeholders[key] = value
+		}
+	}
+	return &clone
+}
+
+// Diff lists the fields that differ between two versions of a mock, using
+// their JSON names. Runtime state such as Status is ignored.
+func Diff(before, after *MockAPI) []FieldChange {
+	var b, a reflect.Value
+	if before != nil {
+		b = reflect.ValueOf(*before)
+	}
+	if after != nil {
+		a = reflect.ValueOf(*after)
+	}
+
+	typ := reflect.TypeOf(MockAPI{})
+	var changes []FieldChange
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || name == "status" || name == "source" {
+			continue
+		}
+
+		var bv, av interface{}
+		if b.IsValid() && !b.Field(i).IsZero() {
+			bv = b.Field(i).Interface()
+		}
+		if a.IsValid() && !a.Field(i).IsZero() {
+			av = a.Field(i).Interface()
+		}
+		if reflect.DeepEqual(bv, av) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, Before: bv, After: av})
+	}
+	return changes
+}
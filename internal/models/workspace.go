@@ -0,0 +1,12 @@
+package models
+
+import "regexp"
+
+// workspacePattern restricts workspace names to characters that are safe in
+// URL paths and file names
+var workspacePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidWorkspace reports whether name is a valid workspace name
+func ValidWorkspace(name string) bool {
+	return workspacePattern.MatchString(name)
+}
@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// ImportOptions controls how operations are turned into mocks
+type ImportOptions struct {
+	Port       int    // Port shared by all generated mocks
+	Protocol   string // http (default) or https
+	CertFile   string // HTTPS certificate file path
+	KeyFile    string // HTTPS private key file path
+	NamePrefix string // Prepended to every mock name
+	SpecPath   string // If set, requests to the mocks are validated against this document
+}
+
+// BuildMocks generates one mock definition per operation in the document
+func (d *Document) BuildMocks(opts ImportOptions) ([]*models.CreateMockAPIRequest, error) {
+	if opts.Port < 1 || opts.Port > 65535 {
+		return nil, fmt.Errorf("invalid port: %d", opts.Port)
+	}
+	if opts.Protocol == "" {
+		opts.Protocol = models.ProtocolHTTP
+	}
+
+	ops := d.Operations()
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("spec contains no operations")
+	}
+
+	prefix := d.PathPrefix()
+	reqs := make([]*models.CreateMockAPIRequest, 0, len(ops))
+	for _, op := range ops {
+		statusCode, contentType, body := d.sampleResponse(op.Operation)
+
+		name := op.Operation.OperationID
+		if name == "" {
+			name = op.Operation.Summary
+		}
+		if name == "" {
+			name = op.Method + " " + op.Path
+		}
+
+		reqs = append(reqs, &models.CreateMockAPIRequest{
+			Name:        opts.NamePrefix + name,
+			Port:        opts.Port,
+			Protocol:    opts.Protocol,
+			CertFile:    opts.CertFile,
+			KeyFile:     opts.KeyFile,
+			Content:     body,
+			Charset:     models.CharsetUTF8,
+			Path:        prefix + op.Path,
+			Method:      op.Method,
+			StatusCode:  statusCode,
+			ContentType: contentType,
+			OpenAPISpec: opts.SpecPath,
+		})
+	}
+
+	return reqs, nil
+}
+
+// sampleResponse picks the response to mock for an operation: the lowest 2xx
+// status, then "default", then the lowest declared status
+func (d *Document) sampleResponse(op *Operation) (int, string, string) {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	chosen := ""
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			chosen = code
+			break
+		}
+	}
+	if chosen == "" {
+		if _, exists := op.Responses["default"]; exists {
+			chosen = "default"
+		} else if len(codes) > 0 {
+			chosen = codes[0]
+		}
+	}
+
+	statusCode, err := strconv.Atoi(strings.ReplaceAll(strings.ToUpper(chosen), "X", "0"))
+	if err != nil || statusCode < 100 || statusCode > 599 {
+		statusCode = 200
+	}
+
+	resp := d.ResolveResponse(op.Responses[chosen])
+	if resp == nil {
+		return statusCode, "", ""
+	}
+
+	if d.IsSwagger2() {
+		contentType, body := d.sampleSwagger2(op, resp)
+		return statusCode, contentType, body
+	}
+
+	contentType := pickContentType(resp.Content)
+	if contentType == "" {
+		return statusCode, "", ""
+	}
+	media := resp.Content[contentType]
+
+	var value any
+	switch {
+	case media == nil:
+	case media.Example != nil:
+		value = media.Example
+	case len(media.Examples) > 0:
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if example := d.ResolveExample(media.Examples[names[0]]); example != nil {
+			value = example.Value
+		}
+	default:
+		value = d.Sample(media.Schema)
+	}
+
+	return statusCode, contentType, encodeBody(value, contentType)
+}
+
+// sampleSwagger2 returns the content type and body of a Swagger 2 response
+func (d *Document) sampleSwagger2(op *Operation, resp *Response) (string, string) {
+	produces := op.Produces
+	if len(produces) == 0 {
+		produces = d.Produces
+	}
+
+	contentType := "application/json"
+	for _, candidate := range produces {
+		if isJSON(candidate) {
+			contentType = candidate
+			break
+		}
+	}
+	if !isJSON(contentType) && len(produces) > 0 {
+		contentType = produces[0]
+	}
+
+	var value any
+	if example, exists := resp.Examples[contentType]; exists {
+		value = example
+	} else if resp.Schema != nil {
+		value = d.Sample(resp.Schema)
+	} else {
+		return contentType, ""
+	}
+
+	return contentType, encodeBody(value, contentType)
+}
+
+// pickContentType prefers JSON content, falling back to the first type
+func pickContentType(content MediaMap) string {
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+
+	for _, contentType := range types {
+		if isJSON(contentType) {
+			return contentType
+		}
+	}
+	if len(types) > 0 {
+		return types[0]
+	}
+	return ""
+}
+
+// isJSON reports whether a content type carries JSON
+func isJSON(contentType string) bool {
+	return strings.Contains(contentType, "json")
+}
+
+// encodeBody renders a sample value as response content
+func encodeBody(value any, contentType string) string {
+	if value == nil {
+		return ""
+	}
+	if str, ok := value.(string); ok && !isJSON(contentType) {
+		return str
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
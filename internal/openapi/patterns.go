@@ -0,0 +1,66 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pointerEscaper escapes a key for use in a JSON pointer
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// CheckPatterns compiles the regular expression of every "pattern" keyword
+// in a document. Request validation skips patterns that do not compile, so
+// broken ones would otherwise go unnoticed.
+func CheckPatterns(data []byte) error {
+	var raw any
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(trimmed, &raw)
+	} else {
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse spec: %v", err)
+	}
+	return checkPatterns(raw, "#")
+}
+
+// checkPatterns walks a decoded document; location is its JSON pointer
+func checkPatterns(value any, location string) error {
+	switch value := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := location + "/" + pointerEscaper.Replace(key)
+			// "pattern" may also name a property, whose value is a schema
+			if pattern, ok := value[key].(string); ok && key == "pattern" {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("invalid pattern at %s: %v", child, err)
+				}
+				continue
+			}
+			if err := checkPatterns(value[key], child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range value {
+			if err := checkPatterns(item, location+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
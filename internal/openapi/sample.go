@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"sort"
+)
+
+// maxSampleDepth limits recursion for self-referencing schemas
+const maxSampleDepth = 8
+
+// Sample generates an example value for a schema. Explicit examples,
+// defaults and enum values are preferred over generated values.
+func (d *Document) Sample(schema *Schema) any {
+	return d.sample(schema, 0)
+}
+
+func (d *Document) sample(schema *Schema, depth int) any {
+	schema = d.ResolveSchema(schema)
+	if schema == nil || depth > maxSampleDepth {
+		return nil
+	}
+
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]any)
+		for _, part := range schema.AllOf {
+			if obj, ok := d.sample(part, depth+1).(map[string]any); ok {
+				for key, value := range obj {
+					merged[key] = value
+				}
+			}
+		}
+		if len(schema.Properties) > 0 {
+			for key, value := range d.sampleObject(schema, depth) {
+				merged[key] = value
+			}
+		}
+		return merged
+	}
+	if len(schema.OneOf) > 0 {
+		return d.sample(schema.OneOf[0], depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		return d.sample(schema.AnyOf[0], depth+1)
+	}
+
+	switch schema.Type {
+	case "object":
+		return d.sampleObject(schema, depth)
+	case "array":
+		item := d.sample(schema.Items, depth+1)
+		if item == nil {
+			return []any{}
+		}
+		return []any{item}
+	case "string":
+		return sampleString(schema.Format)
+	case "integer":
+		if schema.Minimum != nil {
+			return int(*schema.Minimum)
+		}
+		return 0
+	case "number":
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return 0.0
+	case "boolean":
+		return true
+	case "":
+		if len(schema.Properties) > 0 {
+			return d.sampleObject(schema, depth)
+		}
+		if schema.Items != nil {
+			return []any{d.sample(schema.Items, depth+1)}
+		}
+	}
+
+	return nil
+}
+
+// sampleObject generates a value for every property of an object schema
+func (d *Document) sampleObject(schema *Schema, depth int) map[string]any {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	obj := make(map[string]any, len(names))
+	for _, name := range names {
+		obj[name] = d.sample(schema.Properties[name], depth+1)
+	}
+	return obj
+}
+
+// sampleString returns a placeholder string for a string format
+func sampleString(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "ipv6":
+		return "::1"
+	case "byte":
+		return "c3RyaW5n"
+	case "password":
+		return "********"
+	}
+	return "string"
+}
@@ -0,0 +1,417 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTP methods in the order they appear in a path item
+var methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// Document is the subset of an OpenAPI 3 or Swagger 2 document used by gomoco
+type Document struct {
+	Swagger  string               `yaml:"swagger"`
+	OpenAPI  string               `yaml:"openapi"`
+	Info     Info                 `yaml:"info"`
+	Servers  []ServerInfo         `yaml:"servers"`
+	Paths    map[string]*PathItem `yaml:"paths"`
+	BasePath string               `yaml:"basePath"` // Swagger 2
+	Produces []string             `yaml:"produces"` // Swagger 2
+	Consumes []string             `yaml:"consumes"` // Swagger 2
+
+	Components  Components            `yaml:"components"`
+	Definitions map[string]*Schema    `yaml:"definitions"` // Swagger 2
+	Parameters  map[string]*Parameter `yaml:"parameters"`  // Swagger 2
+	Responses   map[string]*Response  `yaml:"responses"`   // Swagger 2
+}
+
+// Info holds the document metadata
+type Info struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+// ServerInfo is an OpenAPI 3 server entry
+type ServerInfo struct {
+	URL string `yaml:"url"`
+}
+
+// Components holds reusable OpenAPI 3 objects
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	Responses     map[string]*Response    `yaml:"responses"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	Examples      map[string]*Example     `yaml:"examples"`
+}
+
+// PathItem holds the operations of a single path
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Options    *Operation   `yaml:"options"`
+	Head       *Operation   `yaml:"head"`
+	Patch      *Operation   `yaml:"patch"`
+	Trace      *Operation   `yaml:"trace"`
+}
+
+// Operation is a single API operation
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Tags        []string             `yaml:"tags"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+	Produces    []string             `yaml:"produces"` // Swagger 2
+	Consumes    []string             `yaml:"consumes"` // Swagger 2
+}
+
+// Parameter describes an operation parameter. Swagger 2 non-body
+// parameters carry their type inline instead of in Schema.
+type Parameter struct {
+	Ref      string   `yaml:"$ref"`
+	Name     string   `yaml:"name"`
+	In       string   `yaml:"in"`
+	Required bool     `yaml:"required"`
+	Schema   *Schema  `yaml:"schema"`
+	Type     string   `yaml:"type"`   // Swagger 2
+	Format   string   `yaml:"format"` // Swagger 2
+	Items    *Schema  `yaml:"items"`  // Swagger 2
+	Enum     []any    `yaml:"enum"`   // Swagger 2
+	Minimum  *float64 `yaml:"minimum"`
+	Maximum  *float64 `yaml:"maximum"`
+	Pattern  string   `yaml:"pattern"`
+	Content  MediaMap `yaml:"content"`
+	Example  any      `yaml:"example"`
+}
+
+// RequestBody is an OpenAPI 3 request body
+type RequestBody struct {
+	Ref      string   `yaml:"$ref"`
+	Required bool     `yaml:"required"`
+	Content  MediaMap `yaml:"content"`
+}
+
+// Response describes an operation response
+type Response struct {
+	Ref         string         `yaml:"$ref"`
+	Description string         `yaml:"description"`
+	Content     MediaMap       `yaml:"content"`
+	Headers     map[string]any `yaml:"headers"`
+	Schema      *Schema        `yaml:"schema"`   // Swagger 2
+	Examples    map[string]any `yaml:"examples"` // Swagger 2, keyed by MIME type
+}
+
+// MediaMap maps content types to media type objects
+type MediaMap map[string]*MediaType
+
+// MediaType describes a body for a single content type
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  any                 `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+// Example is a named example value
+type Example struct {
+	Ref     string `yaml:"$ref"`
+	Summary string `yaml:"summary"`
+	Value   any    `yaml:"value"`
+}
+
+// Schema is the subset of JSON Schema used for samples and validation
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 SchemaType         `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Enum                 []any              `yaml:"enum"`
+	Default              any                `yaml:"default"`
+	Example              any                `yaml:"example"`
+	Nullable             bool               `yaml:"nullable"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties any                `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	Pattern              string             `yaml:"pattern"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+}
+
+// SchemaType is a schema type. OpenAPI 3.1 allows a list of types, of which
+// the first non-null entry is used.
+type SchemaType string
+
+// UnmarshalYAML accepts both a single type and a list of types
+func (t *SchemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var types []string
+		if err := node.Decode(&types); err != nil {
+			return err
+		}
+		for _, typ := range types {
+			if typ != "null" {
+				*t = SchemaType(typ)
+				return nil
+			}
+		}
+		return nil
+	}
+	var typ string
+	if err := node.Decode(&typ); err != nil {
+		return err
+	}
+	*t = SchemaType(typ)
+	return nil
+}
+
+// Load reads and parses a specification file
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec file: %v", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses an OpenAPI 3 or Swagger 2 document in YAML or JSON format
+func Parse(data []byte) (*Document, error) {
+	// JSON documents are converted through a generic value, because JSON
+	// with tab indentation is not valid YAML
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var raw any
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse spec: %v", err)
+		}
+		converted, err := yaml.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse spec: %v", err)
+		}
+		data = converted
+	}
+
+	var doc Document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %v", err)
+	}
+
+	if doc.OpenAPI == "" && doc.Swagger == "" {
+		return nil, fmt.Errorf("not an OpenAPI or Swagger document")
+	}
+	if doc.Swagger != "" && !strings.HasPrefix(doc.Swagger, "2.") {
+		return nil, fmt.Errorf("unsupported Swagger version: %s", doc.Swagger)
+	}
+	if doc.OpenAPI != "" && !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version: %s", doc.OpenAPI)
+	}
+
+	return &doc, nil
+}
+
+// IsSwagger2 reports whether the document is a Swagger 2 document
+func (d *Document) IsSwagger2() bool {
+	return d.Swagger != ""
+}
+
+// PathPrefix returns the path prefix from basePath or the first server URL
+func (d *Document) PathPrefix() string {
+	prefix := d.BasePath
+	if !d.IsSwagger2() && len(d.Servers) > 0 {
+		if u, err := url.Parse(d.Servers[0].URL); err == nil {
+			prefix = u.Path
+		}
+	}
+	return strings.TrimSuffix(prefix, "/")
+}
+
+// OperationRef is an operation together with its location in the document
+type OperationRef struct {
+	Method     string
+	Path       string
+	Operation  *Operation
+	Parameters []*Parameter // Path item and operation parameters, resolved
+}
+
+// Operations returns every operation in the document, ordered by path
+func (d *Document) Operations() []OperationRef {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var ops []OperationRef
+	for _, path := range paths {
+		item := d.Paths[path]
+		if item == nil {
+			continue
+		}
+		for _, method := range methods {
+			op := item.operation(method)
+			if op == nil {
+				continue
+			}
+			ops = append(ops, OperationRef{
+				Method:     method,
+				Path:       path,
+				Operation:  op,
+				Parameters: d.mergeParameters(item.Parameters, op.Parameters),
+			})
+		}
+	}
+	return ops
+}
+
+// operation returns the operation for an HTTP method
+func (p *PathItem) operation(method string) *Operation {
+	switch method {
+	case "GET":
+		return p.Get
+	case "PUT":
+		return p.Put
+	case "POST":
+		return p.Post
+	case "DELETE":
+		return p.Delete
+	case "OPTIONS":
+		return p.Options
+	case "HEAD":
+		return p.Head
+	case "PATCH":
+		return p.Patch
+	case "TRACE":
+		return p.Trace
+	}
+	return nil
+}
+
+// mergeParameters resolves path item and operation parameters, letting
+// operation parameters override path item ones with the same name and location
+func (d *Document) mergeParameters(common, own []*Parameter) []*Parameter {
+	merged := make([]*Parameter, 0, len(common)+len(own))
+	index := make(map[string]int)
+	for _, list := range [][]*Parameter{common, own} {
+		for _, param := range list {
+			param = d.ResolveParameter(param)
+			if param == nil {
+				continue
+			}
+			key := param.In + ":" + param.Name
+			if i, exists := index[key]; exists {
+				merged[i] = param
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, param)
+		}
+	}
+	return merged
+}
+
+// refName returns the last component of a local reference if it starts with prefix
+func refName(ref, prefix string) (string, bool) {
+	if !strings.HasPrefix(ref, prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(ref, prefix)
+	// Unescape JSON pointer tokens
+	name = strings.ReplaceAll(name, "~1", "/")
+	name = strings.ReplaceAll(name, "~0", "~")
+	return name, true
+}
+
+// maxRefDepth guards against reference cycles
+const maxRefDepth = 32
+
+// ResolveSchema follows $ref until a concrete schema is found
+func (d *Document) ResolveSchema(s *Schema) *Schema {
+	for i := 0; s != nil && s.Ref != "" && i < maxRefDepth; i++ {
+		if name, ok := refName(s.Ref, "#/components/schemas/"); ok {
+			s = d.Components.Schemas[name]
+		} else if name, ok := refName(s.Ref, "#/definitions/"); ok {
+			s = d.Definitions[name]
+		} else {
+			return nil
+		}
+	}
+	return s
+}
+
+// ResolveParameter follows $ref until a concrete parameter is found
+func (d *Document) ResolveParameter(p *Parameter) *Parameter {
+	for i := 0; p != nil && p.Ref != "" && i < maxRefDepth; i++ {
+		if name, ok := refName(p.Ref, "#/components/parameters/"); ok {
+			p = d.Components.Parameters[name]
+		} else if name, ok := refName(p.Ref, "#/parameters/"); ok {
+			p = d.Parameters[name]
+		} else {
+			return nil
+		}
+	}
+	return p
+}
+
+// ResolveResponse follows $ref until a concrete response is found
+func (d *Document) ResolveResponse(r *Response) *Response {
+	for i := 0; r != nil && r.Ref != "" && i < maxRefDepth; i++ {
+		if name, ok := refName(r.Ref, "#/components/responses/"); ok {
+			r = d.Components.Responses[name]
+		} else if name, ok := refName(r.Ref, "#/responses/"); ok {
+			r = d.Responses[name]
+		} else {
+			return nil
+		}
+	}
+	return r
+}
+
+// ResolveRequestBody follows $ref until a concrete request body is found
+func (d *Document) ResolveRequestBody(b *RequestBody) *RequestBody {
+	for i := 0; b != nil && b.Ref != "" && i < maxRefDepth; i++ {
+		name, ok := refName(b.Ref, "#/components/requestBodies/")
+		if !ok {
+			return nil
+		}
+		b = d.Components.RequestBodies[name]
+	}
+	return b
+}
+
+// ResolveExample follows $ref until a concrete example is found
+func (d *Document) ResolveExample(e *Example) *Example {
+	for i := 0; e != nil && e.Ref != "" && i < maxRefDepth; i++ {
+		name, ok := refName(e.Ref, "#/components/examples/")
+		if !ok {
+			return nil
+		}
+		e = d.Components.Examples[name]
+	}
+	return e
+}
+
+// remarshal decodes a generic value such as an inline schema into a typed structure
+func remarshal(in any, out any) error {
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, out)
+}
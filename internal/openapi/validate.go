@@ -0,0 +1,419 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes a single contract violation
+type ValidationError struct {
+	Location string `json:"location"` // e.g. "path.id", "query.limit", "header.X-Token", "body.items[0].name"
+	Message  string `json:"message"`
+}
+
+// FindOperation returns the operation matching a request method and path,
+// together with the values of the path template parameters
+func (d *Document) FindOperation(method, path string) (*OperationRef, map[string]string) {
+	prefix := d.PathPrefix()
+	if prefix != "" {
+		if !strings.HasPrefix(path, prefix) {
+			return nil, nil
+		}
+		path = strings.TrimPrefix(path, prefix)
+	}
+
+	var best *OperationRef
+	var bestParams map[string]string
+	bestLiterals := -1
+	for _, op := range d.Operations() {
+		if !strings.EqualFold(op.Method, method) {
+			continue
+		}
+		params, literals, ok := matchTemplate(op.Path, path)
+		if !ok || literals <= bestLiterals {
+			continue
+		}
+		op := op
+		best, bestParams, bestLiterals = &op, params, literals
+	}
+	return best, bestParams
+}
+
+// matchTemplate matches a path against a template such as "/pets/{id}"
+func matchTemplate(template, path string) (map[string]string, int, bool) {
+	templateSegs := strings.Split(strings.Trim(template, "/"), "/")
+	pathSegs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateSegs) != len(pathSegs) {
+		return nil, 0, false
+	}
+
+	params := make(map[string]string)
+	literals := 0
+	for i, seg := range templateSegs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			if pathSegs[i] == "" {
+				return nil, 0, false
+			}
+			params[seg[1:len(seg)-1]] = pathSegs[i]
+			continue
+		}
+		if seg != pathSegs[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+// ValidateRequest checks a request against the operation it maps to in the
+// document. The body must be passed separately because it has already been read.
+func (d *Document) ValidateRequest(r *http.Request, body []byte) []ValidationError {
+	op, pathParams := d.FindOperation(r.Method, r.URL.Path)
+	if op == nil {
+		return []ValidationError{{
+			Location: "path",
+			Message:  fmt.Sprintf("no operation matches %s %s", r.Method, r.URL.Path),
+		}}
+	}
+
+	var errs []ValidationError
+	query := r.URL.Query()
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "path":
+			value, exists := pathParams[param.Name]
+			errs = append(errs, d.validateParameter(param, "path", []string{value}, exists)...)
+		case "query":
+			values, exists := query[param.Name]
+			errs = append(errs, d.validateParameter(param, "query", values, exists)...)
+		case "header":
+			values := r.Header.Values(param.Name)
+			errs = append(errs, d.validateParameter(param, "header", values, len(values) > 0)...)
+		case "body":
+			// Swagger 2 body parameter
+			errs = append(errs, d.validateBody(param.Required, param.Schema, body)...)
+		}
+	}
+
+	if reqBody := d.ResolveRequestBody(op.Operation.RequestBody); reqBody != nil {
+		errs = append(errs, d.validateContent(reqBody, r.Header.Get("Content-Type"), body)...)
+	}
+
+	return errs
+}
+
+// validateParameter checks a path, query or header parameter
+func (d *Document) validateParameter(param *Parameter, in string, values []string, exists bool) []ValidationError {
+	location := in + "." + param.Name
+	if !exists {
+		if param.Required || in == "path" {
+			return []ValidationError{{Location: location, Message: "required parameter is missing"}}
+		}
+		return nil
+	}
+
+	schema := d.parameterSchema(param)
+	if schema == nil {
+		return nil
+	}
+
+	var value any
+	if schema.Type == "array" {
+		// Accept both exploded (?a=1&a=2) and comma separated (?a=1,2) arrays
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items := make([]any, 0, len(values))
+		for _, v := range values {
+			items = append(items, coerce(d.ResolveSchema(schema.Items), v))
+		}
+		value = items
+	} else if len(values) > 0 {
+		value = coerce(schema, values[0])
+	}
+
+	return d.ValidateValue(schema, value, location)
+}
+
+// parameterSchema returns the schema of a parameter, building one from the
+// inline Swagger 2 fields if needed
+func (d *Document) parameterSchema(param *Parameter) *Schema {
+	if param.Schema != nil {
+		return d.ResolveSchema(param.Schema)
+	}
+	if param.Type == "" {
+		return nil
+	}
+	return &Schema{
+		Type:    SchemaType(param.Type),
+		Format:  param.Format,
+		Items:   param.Items,
+		Enum:    param.Enum,
+		Minimum: param.Minimum,
+		Maximum: param.Maximum,
+		Pattern: param.Pattern,
+	}
+}
+
+// coerce converts a raw parameter string to the type declared by the schema.
+// Values that fail to convert are returned unchanged so the type check reports them.
+func coerce(schema *Schema, raw string) any {
+	if schema == nil {
+		return raw
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// validateContent checks an OpenAPI 3 request body
+func (d *Document) validateContent(reqBody *RequestBody, contentType string, body []byte) []ValidationError {
+	if len(body) == 0 {
+		if reqBody.Required {
+			return []ValidationError{{Location: "body", Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	if len(reqBody.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, exists := reqBody.Content[mediaType]
+	if !exists {
+		// Fall back to wildcard entries such as "application/*" or "*/*"
+		for pattern, candidate := range reqBody.Content {
+			if matchMediaRange(pattern, mediaType) {
+				media, exists = candidate, true
+				break
+			}
+		}
+	}
+	if !exists {
+		supported := make([]string, 0, len(reqBody.Content))
+		for t := range reqBody.Content {
+			supported = append(supported, t)
+		}
+		sort.Strings(supported)
+		return []ValidationError{{
+			Location: "header.Content-Type",
+			Message:  fmt.Sprintf("unsupported content type %q, expected one of %s", contentType, strings.Join(supported, ", ")),
+		}}
+	}
+
+	if media == nil || media.Schema == nil || !isJSON(mediaType) {
+		return nil
+	}
+	return d.validateBody(reqBody.Required, media.Schema, body)
+}
+
+// matchMediaRange matches a media type against a range such as "application/*"
+func matchMediaRange(pattern, mediaType string) bool {
+	if pattern == "*/*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+// validateBody decodes a JSON body and checks it against a schema
+func (d *Document) validateBody(required bool, schema *Schema, body []byte) []ValidationError {
+	if len(body) == 0 {
+		if required {
+			return []ValidationError{{Location: "body", Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []ValidationError{{Location: "body", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	return d.ValidateValue(schema, value, "body")
+}
+
+// ValidateValue checks a decoded JSON value against a schema
+func (d *Document) ValidateValue(schema *Schema, value any, location string) []ValidationError {
+	return d.validateValue(schema, value, location, 0)
+}
+
+func (d *Document) validateValue(schema *Schema, value any, location string, depth int) []ValidationError {
+	schema = d.ResolveSchema(schema)
+	if schema == nil || depth > maxRefDepth {
+		return nil
+	}
+
+	fail := func(format string, args ...any) []ValidationError {
+		return []ValidationError{{Location: location, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fail("must not be null")
+	}
+
+	var errs []ValidationError
+	for _, part := range schema.AllOf {
+		errs = append(errs, d.validateValue(part, value, location, depth+1)...)
+	}
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		for _, part := range schema.OneOf {
+			if len(d.validateValue(part, value, location, depth+1)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			errs = append(errs, fail("must match exactly one schema in oneOf, matched %d", matched)...)
+		}
+	}
+	if len(schema.AnyOf) > 0 {
+		matched := false
+		for _, part := range schema.AnyOf {
+			if len(d.validateValue(part, value, location, depth+1)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fail("must match at least one schema in anyOf")...)
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		errs = append(errs, fail("must be one of %v", schema.Enum)...)
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return append(errs, fail("must be an object")...)
+		}
+		errs = append(errs, d.validateObject(schema, obj, location, depth)...)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return append(errs, fail("must be an array")...)
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			errs = append(errs, fail("must contain at least %d items", *schema.MinItems)...)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			errs = append(errs, fail("must contain at most %d items", *schema.MaxItems)...)
+		}
+		for i, item := range items {
+			errs = append(errs, d.validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", location, i), depth+1)...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(errs, fail("must be a string")...)
+		}
+		length := len([]rune(str))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			errs = append(errs, fail("must be at least %d characters", *schema.MinLength)...)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			errs = append(errs, fail("must be at most %d characters", *schema.MaxLength)...)
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(str) {
+				errs = append(errs, fail("must match pattern %s", schema.Pattern)...)
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return append(errs, fail("must be a %s", schema.Type)...)
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return append(errs, fail("must be an integer")...)
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			errs = append(errs, fail("must be >= %v", *schema.Minimum)...)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			errs = append(errs, fail("must be <= %v", *schema.Maximum)...)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(errs, fail("must be a boolean")...)
+		}
+	case "":
+		if obj, ok := value.(map[string]any); ok && len(schema.Properties) > 0 {
+			errs = append(errs, d.validateObject(schema, obj, location, depth)...)
+		}
+	}
+
+	return errs
+}
+
+// validateObject checks required, declared and additional properties
+func (d *Document) validateObject(schema *Schema, obj map[string]any, location string, depth int) []ValidationError {
+	var errs []ValidationError
+	for _, name := range schema.Required {
+		if _, exists := obj[name]; !exists {
+			errs = append(errs, ValidationError{
+				Location: location + "." + name,
+				Message:  "required property is missing",
+			})
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := location + "." + name
+		if prop, declared := schema.Properties[name]; declared {
+			errs = append(errs, d.validateValue(prop, obj[name], child, depth+1)...)
+			continue
+		}
+		switch additional := schema.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, ValidationError{Location: child, Message: "additional property is not allowed"})
+			}
+		case map[string]any:
+			var extra Schema
+			if err := remarshal(additional, &extra); err == nil {
+				errs = append(errs, d.validateValue(&extra, obj[name], child, depth+1)...)
+			}
+		}
+	}
+	return errs
+}
+
+// inEnum reports whether a value equals one of the enum entries
+func inEnum(enum []any, value any) bool {
+	for _, candidate := range enum {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/validation"
+)
+
+// ErrForbidden is returned, wrapped, when an actor may not perform a change
+var ErrForbidden = errors.New("forbidden")
+
+// Authorize returns an error wrapping ErrForbidden if the actor may not
+// change the mock. A nil mock checks whether the actor may create mocks.
+// Admins may change every mock, editors the mocks they own or that belong
+// to one of their teams, and viewers none.
+func (m *Manager) Authorize(actor *models.Actor, mock *models.MockAPI) error {
+	if actor == nil || actor.Role == "" || actor.Role == models.RoleAdmin {
+		return nil
+	}
+	if actor.Role != models.RoleEditor {
+		return fmt.Errorf("%w: %s may not change mocks", ErrForbidden, actor.Name)
+	}
+	if mock == nil || mock.Owner == actor.Name || (mock.Team != "" && actor.InTeam(mock.Team)) {
+		return nil
+	}
+	if mock.Team != "" {
+		return fmt.Errorf("%w: mock %q belongs to team %s", ErrForbidden, mock.Name, mock.Team)
+	}
+	return fmt.Errorf("%w: mock %q belongs to %s", ErrForbidden, mock.Name, ownerName(mock))
+}
+
+// AuthorizeAdmin returns an error wrapping ErrForbidden unless the actor may
+// change the server configuration, e.g. reload it
+func (m *Manager) AuthorizeAdmin(actor *models.Actor) error {
+	if actor == nil || actor.Role == "" || actor.Role == models.RoleAdmin {
+		return nil
+	}
+	return fmt.Errorf("%w: only admins may do this", ErrForbidden)
+}
+
+// assignOwner sets the owner and team of a new mock. Editors may only
+// assign their own teams; the team defaults to the creator's first team.
+func assignOwner(mock *models.MockAPI, team string, actor *models.Actor) error {
+	if actor == nil || actor.Role == "" {
+		mock.Team = team
+		return nil
+	}
+
+	mock.Owner = actor.Name
+	if team == "" && len(actor.Teams) > 0 {
+		team = actor.Teams[0]
+	}
+	if team != "" && actor.Role != models.RoleAdmin && !actor.InTeam(team) {
+		return fmt.Errorf("%w: %s is not a member of team %s", ErrForbidden, actor.Name, team)
+	}
+	mock.Team = team
+	return nil
+}
+
+// checkOwnerChange returns an error wrapping ErrForbidden if the actor may
+// not hand a mock over to another owner or team. Only admins may change the
+// owner; editors may move a mock to another of their teams.
+func checkOwnerChange(mock, next *models.MockAPI, actor *models.Actor) error {
+	if actor == nil || actor.Role == "" || actor.Role == models.RoleAdmin {
+		return nil
+	}
+	if next.Owner != mock.Owner {
+		return fmt.Errorf("%w: only admins may change the owner of a mock", ErrForbidden)
+	}
+	if next.Team != mock.Team && !actor.InTeam(next.Team) {
+		if next.Team == "" {
+			return fmt.Errorf("%w: only admins may remove a mock from its team", ErrForbidden)
+		}
+		return fmt.Errorf("%w: %s is not a member of team %s", ErrForbidden, actor.Name, next.Team)
+	}
+	return nil
+}
+
+// ownerName describes the owner of a mock for error messages
+func ownerName(mock *models.MockAPI) string {
+	if mock.Owner == "" {
+		return "no one (only admins may change it)"
+	}
+	return mock.Owner
+}
+
+// checkPaths returns a validation error if an FTP/SFTP root directory or
+// the Unix socket of a mock is outside the data directory. A path the
+// current version already had is allowed, so mocks from the config keep
+// working.
+func (m *Manager) checkPaths(current, mock *models.MockAPI) error {
+	if m.dataDir == "" {
+		return nil
+	}
+	paths := []struct{ field, path, currentPath string }{
+		{"ftp_root_dir", mock.FTPRootDir, ""},
+		{"sftp_root_dir", mock.SFTPRootDir, ""},
+		{"unix_socket", mock.UnixSocket, ""},
+	}
+	if current != nil {
+		paths[0].currentPath, paths[1].currentPath, paths[2].currentPath = current.FTPRootDir, current.SFTPRootDir, current.UnixSocket
+	}
+	for _, p := range paths {
+		if p.path == "" || p.path == p.currentPath {
+			continue
+		}
+		if !insideDir(m.dataDir, p.path) {
+			return validation.Field(p.field, validation.CodeInvalid, "must be inside the data directory %s", m.dataDir)
+		}
+	}
+	return nil
+}
+
+// insideDir reports whether path is base or inside it, following the
+// symbolic links of the part of path that exists
+func insideDir(base, path string) bool {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(base, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePath returns the absolute form of a path with the symbolic links
+// of its longest existing prefix resolved
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(resolved, rest), nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("no part of %s exists", path)
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
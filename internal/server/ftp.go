@@ -1,14 +1,15 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
-	"strconv"
-	"strings"
 
-	"gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/validation"
 
 	filedriver "github.com/goftp/file-driver"
 	"github.com/goftp/server"
@@ -21,21 +22,10 @@ type FTPServer struct {
 	running bool
 }
 
-// NewFTPServer creates a new FTP server
-func NewFTPServer(mock *models.MockAPI) (*FTPServer, error) {
-	// Set default values
-	if mock.FTPMode == "" {
-		mock.FTPMode = models.FTPModePassive
-	}
-	if mock.FTPRootDir == "" {
-		mock.FTPRootDir = filepath.Join("ftp_data", fmt.Sprintf("port_%d", mock.Port))
-	}
-	if mock.FTPUser == "" {
-		mock.FTPUser = "admin"
-	}
-	if mock.FTPPass == "" {
-		mock.FTPPass = "admin"
-	}
+// NewFTPServer creates a new FTP server listening on the mock's bind host,
+// or on defaultHost if it has none
+func NewFTPServer(mock *models.MockAPI, defaultHost string) (*FTPServer, error) {
+	applyFTPDefaults(mock)
 
 	// Create FTP root directory if it doesn't exist
 	if err := os.MkdirAll(mock.FTPRootDir, 0755); err != nil {
@@ -48,30 +38,39 @@ func NewFTPServer(mock *models.MockAPI) (*FTPServer, error) {
 		Perm:     server.NewSimplePerm("user", "group"),
 	}
 
+	host := mock.BindHost
+	if host == "" {
+		host = defaultHost
+	}
+	if host == "" {
+		host = "0.0.0.0"
+	}
+
 	// Configure FTP server options
 	opts := &server.ServerOpts{
 		Factory:  factory,
 		Port:     mock.Port,
-		Hostname: "0.0.0.0",
+		Hostname: host,
 		Auth:     &ftpAuth{user: mock.FTPUser, pass: mock.FTPPass},
 	}
+	// Announce the bind address in PASV replies, which only carry IPv4
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil && !ip.IsUnspecified() {
+		opts.PublicIp = host
+	}
 
 	// Configure passive mode
 	if mock.FTPMode == models.FTPModePassive {
 		if mock.FTPPassivePortRange != "" {
 			// Parse port range (e.g., "50000-50100")
-			parts := strings.Split(mock.FTPPassivePortRange, "-")
-			if len(parts) == 2 {
-				minPort, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
-				maxPort, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
-				if err1 == nil && err2 == nil && minPort > 0 && maxPort > minPort {
-					opts.PassivePorts = fmt.Sprintf("%d-%d", minPort, maxPort)
-				}
+			minPort, maxPort, err := validation.PortRange(mock.FTPPassivePortRange)
+			if err != nil {
+				return nil, err
 			}
+			opts.PassivePorts = fmt.Sprintf("%d-%d", minPort, maxPort)
 		}
 		// Default passive port range if not specified
 		if opts.PassivePorts == "" {
-			opts.PassivePorts = "50000-50100"
+			opts.PassivePorts = defaultPassivePorts
 		}
 	}
 
@@ -83,6 +82,22 @@ func NewFTPServer(mock *models.MockAPI) (*FTPServer, error) {
 	}, nil
 }
 
+// applyFTPDefaults fills in the FTP settings left empty
+func applyFTPDefaults(mock *models.MockAPI) {
+	if mock.FTPMode == "" {
+		mock.FTPMode = models.FTPModePassive
+	}
+	if mock.FTPRootDir == "" {
+		mock.FTPRootDir = filepath.Join("ftp_data", fmt.Sprintf("port_%d", mock.Port))
+	}
+	if mock.FTPUser == "" {
+		mock.FTPUser = "admin"
+	}
+	if mock.FTPPass == "" {
+		mock.FTPPass = "admin"
+	}
+}
+
 // Start starts the FTP server
 func (s *FTPServer) Start() error {
 	s.running = true
@@ -107,6 +122,11 @@ func (s *FTPServer) Stop() error {
 	return nil
 }
 
+// StopContext stops the FTP server like Stop, returning once ctx is done
+func (s *FTPServer) StopContext(ctx context.Context) error {
+	return stopWithin(ctx, s.Stop)
+}
+
 // IsRunning returns whether the FTP server is running
 func (s *FTPServer) IsRunning() bool {
 	return s.running
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/storage"
+)
+
+// record appends a revision for a change to a mock. Failures are logged but
+// do not fail the change itself.
+func (m *Manager) record(action string, actor *models.Actor, before, after *models.MockAPI) {
+	if actor == nil {
+		actor = models.SystemActor
+	}
+
+	rev := &models.Revision{
+		Action:  action,
+		Actor:   *actor,
+		Time:    time.Now(),
+		Before:  before.Clone(),
+		After:   after.Clone(),
+		Changes: models.Diff(before, after),
+	}
+	for _, mock := range []*models.MockAPI{rev.Before, rev.After} {
+		if mock != nil {
+			rev.MockID, rev.MockName = mock.ID, mock.Name
+			mock.Status = ""
+		}
+	}
+
+	if err := m.storage.AppendRevision(rev); err != nil {
+		log.Printf("Warning: Failed to record revision of mock %s: %v", rev.MockID, err)
+	}
+}
+
+// Revisions returns the change history, newest first. If mockID is not
+// empty, only that mock's revisions are returned; this also works for
+// deleted mocks.
+func (m *Manager) Revisions(mockID string) ([]*models.Revision, error) {
+	return m.storage.Revisions(mockID)
+}
+
+// Rollback restores a mock to the state recorded by a revision and restarts
+// it. A deleted mock is recreated with its original ID. Rolling back to a
+// deletion is not possible; pick the revision before it instead.
+func (m *Manager) Rollback(revisionID int64, actor *models.Actor) (*models.MockAPI, error) {
+	rev, err := m.storage.Revision(revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if rev.After == nil {
+		return nil, fmt.Errorf("revision %d deleted the mock; roll back to an earlier revision", revisionID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Revisions are recorded in the at rest form, in which only placeholders
+	// taken from the config are left unescaped
+	target := rev.After.Clone()
+	if err := storage.ResolvePlaceholders(target); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	current, exists := m.mocks[target.ID]
+
+	// The actor must be allowed to change both the current and the restored version
+	if exists {
+		if err := m.Authorize(actor, current); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.Authorize(actor, target); err != nil {
+		return nil, err
+	}
+
+	// Stop the current version, keeping it to restore if the target fails to start
+	if exists {
+		target.Source = current.Source
+		if current.Status == "running" {
+			if err := m.stopServer(current.ID); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if target.Source, err = m.storage.SourcePath(""); err != nil {
+			return nil, err
+		}
+	}
+
+	target.Status = "stopped"
+	if err := m.checkPort(target.ID, target); err != nil {
+		m.restore(current)
+		return nil, err
+	}
+	if err := m.startServer(target); err != nil {
+		m.restore(current)
+		return nil, fmt.Errorf("failed to start restored mock: %v", err)
+	}
+	target.Status = "running"
+	m.mocks[target.ID] = target
+
+	m.record(models.ActionRollback, actor, current, target)
+
+	// Save to storage
+	if err := m.saveToStorage(); err != nil {
+		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+	}
+
+	return target.Clone(), nil
+}
+
+// restore restarts a mock that was stopped for a change that failed
+func (m *Manager) restore(mock *models.MockAPI) {
+	if mock == nil || mock.Status != "running" {
+		return
+	}
+	if err := m.startServer(mock); err != nil {
+		log.Printf("Warning: Failed to restart mock %s (%s): %v", mock.Name, mock.ID, err)
+		mock.Status = "stopped"
+	}
+}
@@ -1,105 +1,392 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
-	"gomoco/internal/models"
-	"gomoco/internal/utils"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/openapi"
+	"github.com/Bahtya/Gomoco/internal/utils"
+	"io"
+	"log"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 )
 
-// HTTPServer represents an HTTP mock server
+// HTTPServer represents an HTTP mock endpoint. Endpoints on the same port
+// share a single listener, so one port can serve many paths.
 type HTTPServer struct {
-	mock   *models.MockAPI
-	server *http.Server
+	mock       *models.MockAPI
+	network    string
+	address    string
+	host       *httpHost
+	spec       *openapi.Document
+	violations *ViolationLog
+	requests   *RequestLog // nil unless requests are captured
 }
 
-// NewHTTPServer creates a new HTTP server
-func NewHTTPServer(mock *models.MockAPI) (*HTTPServer, error) {
+// httpHost is the listener shared by all HTTP endpoints on an address
+type httpHost struct {
+	network  string
+	address  string
+	protocol string
+	certFile string // HTTPS certificate every endpoint on the listener shares
+	keyFile  string
+	mu       sync.RWMutex
+	routes   []*HTTPServer
+	server   *http.Server
+	listener net.Listener
+}
+
+// maxValidationBody is the largest request body read for validation
+const maxValidationBody = 10 * 1024 * 1024
+
+var (
+	httpHostsMu sync.Mutex
+	httpHosts   = make(map[string]*httpHost) // keyed by network and address
+)
+
+// NewHTTPServer creates a new HTTP server listening on the mock's Unix
+// socket or bind host, or on defaultHost if it has neither
+func NewHTTPServer(mock *models.MockAPI, defaultHost string) (*HTTPServer, error) {
+	network, address := listenAddress(mock, defaultHost)
 	return &HTTPServer{
-		mock: mock,
+		mock:       mock,
+		network:    network,
+		address:    address,
+		violations: &ViolationLog{},
 	}, nil
 }
 
 // Start starts the HTTP server
 func (s *HTTPServer) Start() error {
-	mux := http.NewServeMux()
+	// Load the contract requests are validated against
+	if s.mock.OpenAPISpec != "" {
+		spec, err := openapi.Load(s.mock.OpenAPISpec)
+		if err != nil {
+			return fmt.Errorf("failed to load OpenAPI spec: %v", err)
+		}
+		s.spec = spec
+	}
+
+	httpHostsMu.Lock()
+	defer httpHostsMu.Unlock()
+
+	key := s.network + ":" + s.address
+	host, exists := httpHosts[key]
+	if exists {
+		if host.protocol != s.mock.Protocol {
+			return fmt.Errorf("%s is already serving %s", s.address, host.protocol)
+		}
+		// A listener presents a single certificate
+		if host.protocol == models.ProtocolHTTPS && (host.certFile != s.mock.CertFile || host.keyFile != s.mock.KeyFile) {
+			return fmt.Errorf("%s is already serving HTTPS with certificate %s; mocks sharing it must use the same cert_file and key_file", s.address, host.certFile)
+		}
+	} else {
+		host = &httpHost{
+			network:  s.network,
+			address:  s.address,
+			protocol: s.mock.Protocol,
+			certFile: s.mock.CertFile,
+			keyFile:  s.mock.KeyFile,
+		}
+		if err := host.start(s.mock); err != nil {
+			return err
+		}
+		httpHosts[key] = host
+	}
 
-	path := s.mock.Path
-	if path == "" {
-		path = "/"
+	host.mu.Lock()
+	host.routes = append(host.routes, s)
+	host.mu.Unlock()
+
+	s.host = host
+	return nil
+}
+
+// Stop stops the HTTP server, waiting up to 5 seconds for its requests to
+// finish
+func (s *HTTPServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.StopContext(ctx)
+}
+
+// StopContext stops the HTTP server. Once the last endpoint of a listener
+// is gone, the listener is shut down, waiting for its requests to finish
+// until ctx is done and closing the remaining connections then.
+func (s *HTTPServer) StopContext(ctx context.Context) error {
+	host := s.detach()
+	if host == nil {
+		return nil
 	}
 
-	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+	err := host.server.Shutdown(ctx)
+	if err != nil {
+		host.server.Close()
+	}
+	// Serve may not have taken over the listener yet, so close it here too
+	host.listener.Close()
+	return err
+}
+
+// detach removes the endpoint from its listener and returns the listener
+// if it has no endpoints left
+func (s *HTTPServer) detach() *httpHost {
+	if s.host == nil {
+		return nil
+	}
+
+	httpHostsMu.Lock()
+	defer httpHostsMu.Unlock()
+
+	host := s.host
+	s.host = nil
+
+	host.mu.Lock()
+	for i, route := range host.routes {
+		if route == s {
+			host.routes = append(host.routes[:i], host.routes[i+1:]...)
+			break
+		}
+	}
+	remaining := len(host.routes)
+	host.mu.Unlock()
+
+	// The listener is shut down once its last endpoint is gone
+	if remaining > 0 {
+		return nil
+	}
+	delete(httpHosts, host.network+":"+host.address)
+	return host
+}
+
+// IsRunning checks if the server is running
+func (s *HTTPServer) IsRunning() bool {
+	return s.host != nil
+}
+
+// start opens the listener for the host. HTTPS certificates are loaded
+// first, so a missing or broken pair fails the start.
+func (h *httpHost) start(mock *models.MockAPI) error {
+	h.server = &http.Server{
+		Handler: h,
+	}
+
+	if h.protocol == models.ProtocolHTTPS {
+		if mock.CertFile == "" || mock.KeyFile == "" {
+			return fmt.Errorf("failed to start HTTPS server: certificate or key file not specified")
+		}
+		cert, err := tls.LoadX509KeyPair(mock.CertFile, mock.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load HTTPS certificate: %v", err)
+		}
+		h.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	listener, err := listen(h.network, h.address)
+	if err != nil {
+		return fmt.Errorf("failed to start %s server: %v", h.protocol, err)
+	}
+	h.listener = listener
+
+	go func() {
+		var err error
+		if h.protocol == models.ProtocolHTTPS {
+			// HTTPS server, using the certificate in TLSConfig
+			err = h.server.ServeTLS(listener, "", "")
+		} else {
+			// HTTP server
+			err = h.server.Serve(listener)
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("%s server error on %s: %v", h.protocol, h.address, err)
+		}
+	}()
+
+	return nil
+}
+
+// ServeHTTP dispatches a request to the best matching endpoint
+func (h *httpHost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	var best *HTTPServer
+	bestScore := -1
+	pathMatched := false
+	for _, route := range h.routes {
+		score, ok := matchPath(route.mock.Path, r.URL.Path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
 		// Check method if specified
-		if s.mock.Method != "" && r.Method != s.mock.Method {
+		if route.mock.Method != "" && !strings.EqualFold(r.Method, route.mock.Method) {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = route, score
+		}
+	}
+	h.mu.RUnlock()
+
+	if best == nil {
+		if pathMatched {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		http.NotFound(w, r)
+		return
+	}
+
+	best.serve(w, r)
+}
 
-		// Convert content to appropriate charset
-		content, err := utils.ConvertCharset(s.mock.Content, s.mock.Charset)
+// serve writes the mock response
+func (s *HTTPServer) serve(w http.ResponseWriter, r *http.Request) {
+	if s.requests != nil {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxValidationBody))
 		if err != nil {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
 			return
 		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		s.requests.Add(Request{
+			Time:       time.Now(),
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.RawQuery,
+			Header:     r.Header.Clone(),
+			Body:       body,
+		})
+	}
+
+	if s.spec != nil && !s.validate(w, r) {
+		return
+	}
+
+	// Convert content to appropriate charset
+	content, err := utils.ConvertCharset(s.mock.Content, s.mock.Charset)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	for key, value := range s.mock.Headers {
+		w.Header().Set(key, value)
+	}
 
-		// Set content type based on charset
-		contentType := "text/plain"
+	// Set content type based on charset
+	contentType := s.mock.ContentType
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	if !strings.Contains(contentType, "charset=") {
 		if s.mock.Charset == models.CharsetGBK {
 			contentType += "; charset=GBK"
 		} else {
 			contentType += "; charset=UTF-8"
 		}
-		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Type", contentType)
 
-		w.WriteHeader(http.StatusOK)
-		w.Write(content)
-	})
+	statusCode := s.mock.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	w.Write(content)
+}
 
-	s.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.mock.Port),
-		Handler: mux,
+// validate checks the request against the OpenAPI contract. Invalid
+// requests are recorded and answered with the validation status.
+func (s *HTTPServer) validate(w http.ResponseWriter, r *http.Request) bool {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxValidationBody))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return false
 	}
 
-	go func() {
-		var err error
-		if s.mock.Protocol == models.ProtocolHTTPS {
-			// HTTPS server
-			if s.mock.CertFile == "" || s.mock.KeyFile == "" {
-				fmt.Printf("HTTPS server error on port %d: certificate or key file not specified\n", s.mock.Port)
-				return
-			}
-			err = s.server.ListenAndServeTLS(s.mock.CertFile, s.mock.KeyFile)
-		} else {
-			// HTTP server
-			err = s.server.ListenAndServe()
-		}
+	errs := s.spec.ValidateRequest(r, body)
+	if len(errs) == 0 {
+		return true
+	}
 
-		if err != nil && err != http.ErrServerClosed {
-			fmt.Printf("%s server error on port %d: %v\n", s.mock.Protocol, s.mock.Port, err)
-		}
-	}()
+	s.violations.Add(Violation{
+		Time:       time.Now(),
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.RawQuery,
+		Body:       string(body),
+		Errors:     errs,
+	})
 
-	// Give server time to start
-	time.Sleep(100 * time.Millisecond)
-	return nil
+	statusCode := s.mock.ValidationStatus
+	if statusCode == 0 {
+		statusCode = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(statusCode)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(map[string]interface{}{
+		"error":      "request does not match the OpenAPI contract",
+		"violations": errs,
+	})
+	return false
 }
 
-// Stop stops the HTTP server
-func (s *HTTPServer) Stop() error {
-	if s.server == nil {
-		return nil
+// Violations returns the log of requests rejected by contract validation
+func (s *HTTPServer) Violations() *ViolationLog {
+	return s.violations
+}
+
+// matchPath reports whether a request path matches an endpoint path and how
+// specific the match is. Exact paths win over templates such as
+// "/users/{id}", which win over subtree patterns ending in "/".
+func matchPath(pattern, path string) (int, bool) {
+	if pattern == "" {
+		pattern = "/"
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	if pattern == path {
+		return 1 << 30, true
+	}
 
-	return s.server.Shutdown(ctx)
-}
+	if strings.Contains(pattern, "{") {
+		patternSegs := strings.Split(strings.Trim(pattern, "/"), "/")
+		pathSegs := strings.Split(strings.Trim(path, "/"), "/")
+		if len(patternSegs) != len(pathSegs) {
+			return 0, false
+		}
+		literals := 0
+		for i, seg := range patternSegs {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				if pathSegs[i] == "" {
+					return 0, false
+				}
+				continue
+			}
+			if seg != pathSegs[i] {
+				return 0, false
+			}
+			literals++
+		}
+		return 1<<20 + literals, true
+	}
 
-// IsRunning checks if the server is running
-func (s *HTTPServer) IsRunning() bool {
-	return s.server != nil
+	if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) {
+		return len(pattern), true
+	}
+
+	return 0, false
 }
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// hostOf returns the address a mock listens on
+func (m *Manager) hostOf(mock *models.MockAPI) string {
+	if mock.BindHost != "" {
+		return mock.BindHost
+	}
+	return m.bindHost
+}
+
+// listenAddress returns the network and address a mock listens on, using
+// defaultHost for mocks without a bind host
+func listenAddress(mock *models.MockAPI, defaultHost string) (string, string) {
+	if mock.UnixSocket != "" {
+		return "unix", mock.UnixSocket
+	}
+	host := mock.BindHost
+	if host == "" {
+		host = defaultHost
+	}
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(mock.Port))
+}
+
+// sockets records the Unix sockets this process created, the only ones
+// listen may remove
+var sockets = struct {
+	sync.Mutex
+	created map[string]bool
+}{created: make(map[string]bool)}
+
+// listen opens a listener. A Unix socket file this process created before
+// and left behind is removed first; any other existing file is an error.
+func listen(network, address string) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+
+	sockets.Lock()
+	defer sockets.Unlock()
+	if info, err := os.Lstat(address); err == nil {
+		if !sockets.created[address] || info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s already exists", address)
+		}
+		if conn, err := net.Dial("unix", address); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is already in use", address)
+		}
+		os.Remove(address)
+	}
+	listener, err := net.Listen(network, address)
+	if err == nil {
+		sockets.created[address] = true
+	}
+	return listener, err
+}
+
+// isWildcard reports whether a host listens on all interfaces
+func isWildcard(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// hostsOverlap reports whether listeners on two hosts would clash on the
+// same port
+func hostsOverlap(a, b string) bool {
+	return a == b || isWildcard(a) || isWildcard(b)
+}
@@ -1,64 +1,196 @@
 package server
 
 import (
+	"context"
 	"fmt"
-	"gomoco/internal/models"
-	"gomoco/internal/storage"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/storage"
+	"github.com/Bahtya/Gomoco/internal/validation"
 	"log"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // Manager manages all mock servers
 type Manager struct {
-	mu      sync.RWMutex
-	mocks   map[string]*models.MockAPI
-	servers map[string]Server
-	storage *storage.Storage
+	mu         sync.RWMutex
+	mocks      map[string]*models.MockAPI
+	servers    map[string]Server
+	violations map[string]*ViolationLog
+	requests   map[string]*RequestLog
+	storage    storage.Storage
+	reserved   map[int]string // Ports mocks may not use
+	minPort    int            // Range ports are auto-assigned from
+	maxPort    int
+	bindHost   string // Address mocks without a bind host listen on
+	dataDir    string // Directory root directories and sockets set through the API must be inside; empty allows any
+	loadErr    error  // Why the config failed to load; saving is refused until a reload succeeds
+	capture    bool   // Record the requests HTTP and TCP mocks receive
+
+	reloadStatus *ReloadStatus
 }
 
 // Server interface for mock servers
 type Server interface {
 	Start() error
 	Stop() error
+	// StopContext stops the server, waiting for its connections to finish
+	// until ctx is done
+	StopContext(ctx context.Context) error
 	IsRunning() bool
 }
 
-// NewManager creates a new manager instance
-func NewManager() *Manager {
-	store, err := storage.NewStorage()
-	if err != nil {
-		log.Fatalf("Failed to create storage: %v", err)
+// stopWithin runs stop, returning early with the error of ctx once it is done
+func stopWithin(ctx context.Context, stop func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- stop()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
+// Options configure a Manager
+type Options struct {
+	BindHost      string         // Address mocks without a bind host listen on (default all interfaces)
+	ReservedPorts map[int]string // Ports mocks may not use, with what holds them
+	MinPort       int            // Range ports are auto-assigned from (default 20000-29999)
+	MaxPort       int
+	DataDir       string // Directory FTP/SFTP root directories set through the API must be inside (default any)
+	// CaptureRequests records the requests HTTP and TCP mocks receive, for
+	// Requests. It keeps request bodies in memory, so it is meant for tests.
+	CaptureRequests bool
+}
+
+// NewManager creates a new manager instance using the given storage
+func NewManager(store storage.Storage, opts Options) *Manager {
 	m := &Manager{
-		mocks:   make(map[string]*models.MockAPI),
-		servers: make(map[string]Server),
-		storage: store,
+		mocks:      make(map[string]*models.MockAPI),
+		servers:    make(map[string]Server),
+		violations: make(map[string]*ViolationLog),
+		requests:   make(map[string]*RequestLog),
+		storage:    store,
+		reserved:   make(map[int]string),
+		minPort:    opts.MinPort,
+		maxPort:    opts.MaxPort,
+		bindHost:   opts.BindHost,
+		capture:    opts.CaptureRequests,
+	}
+	if m.minPort == 0 && m.maxPort == 0 {
+		m.minPort, m.maxPort = DefaultMinPort, DefaultMaxPort
+	}
+	for port, description := range opts.ReservedPorts {
+		m.reserved[port] = description
+	}
+	if opts.DataDir != "" {
+		dir, err := resolvePath(opts.DataDir)
+		if err != nil {
+			dir = filepath.Clean(opts.DataDir)
+		}
+		m.dataDir = dir
 	}
 
 	// Load existing mocks from storage
 	if err := m.loadFromStorage(); err != nil {
-		log.Printf("Warning: Failed to load mocks from storage: %v", err)
+		log.Printf("Warning: Failed to load mocks from storage, changes will not be saved until it loads: %v", err)
+		m.loadErr = err
 	}
 
 	return m
 }
 
 // Create creates a new mock API
-func (m *Manager) Create(req *models.CreateMockAPIRequest) (*models.MockAPI, error) {
+func (m *Manager) Create(req *models.CreateMockAPIRequest, actor *models.Actor) (*models.MockAPI, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mock, err := m.create(req, actor)
+	if err != nil {
+		return nil, err
+	}
+	m.record(models.ActionCreate, actor, nil, mock)
+
+	// Save to storage
+	if err := m.saveToStorage(); err != nil {
+		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+	}
+
+	return mock.Clone(), nil
+}
+
+// Import creates mocks in a single batch, skipping HTTP definitions whose
+// port, method and path already exist. Once all mocks are created, prepare,
+// if set, is called with each created mock and the index of its request.
+// If any mock fails to start or to be prepared, the mocks created by the
+// batch are removed again.
+func (m *Manager) Import(reqs []*models.CreateMockAPIRequest, actor *models.Actor, prepare func(index int, mock *models.MockAPI) error) ([]*models.MockAPI, int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	created := make([]*models.MockAPI, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	skipped := 0
+	undo := func() {
+		for _, c := range created {
+			m.stopServer(c.ID)
+			delete(m.mocks, c.ID)
+		}
+	}
+	for i, req := range reqs {
+		if m.hasRoute(req.Port, req.Method, req.Path) {
+			skipped++
+			continue
+		}
+
+		mock, err := m.create(req, actor)
+		if err != nil {
+			undo()
+			return nil, 0, fmt.Errorf("failed to import %q: %w", req.Name, err)
+		}
+		created = append(created, mock)
+		indexes = append(indexes, i)
+	}
+	if prepare != nil {
+		for i, mock := range created {
+			if err := prepare(indexes[i], mock); err != nil {
+				undo()
+				return nil, 0, fmt.Errorf("failed to import %q: %w", mock.Name, err)
+			}
+		}
+	}
+	for _, mock := range created {
+		m.record(models.ActionCreate, actor, nil, mock)
+	}
+
+	// Save to storage
+	if err := m.saveToStorage(); err != nil {
+		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+	}
+
+	return cloneMocks(created), skipped, nil
+}
+
+// create creates and starts a mock without persisting it
+func (m *Manager) create(req *models.CreateMockAPIRequest, actor *models.Actor) (*models.MockAPI, error) {
+	if err := m.Authorize(actor, nil); err != nil {
+		return nil, err
+	}
+
 	// Generate unique ID
 	id := uuid.New().String()
 
-	// Check if port is already in use
-	for _, mock := range m.mocks {
-		if mock.Port == req.Port && mock.Status == "running" {
-			return nil, fmt.Errorf("port %d is already in use", req.Port)
-		}
+	source, err := m.storage.SourcePath(req.Source)
+	if err != nil {
+		return nil, err
 	}
 
 	mock := &models.MockAPI{
@@ -66,6 +198,8 @@ func (m *Manager) Create(req *models.CreateMockAPIRequest) (*models.MockAPI, err
 		Name:                req.Name,
 		Port:                req.Port,
 		Protocol:            req.Protocol,
+		BindHost:            req.BindHost,
+		UnixSocket:          req.UnixSocket,
 		CertFile:            req.CertFile,
 		KeyFile:             req.KeyFile,
 		FTPMode:             req.FTPMode,
@@ -82,7 +216,40 @@ func (m *Manager) Create(req *models.CreateMockAPIRequest) (*models.MockAPI, err
 		Charset:             req.Charset,
 		Path:                req.Path,
 		Method:              req.Method,
+		StatusCode:          req.StatusCode,
+		ContentType:         req.ContentType,
+		Headers:             req.Headers,
+		OpenAPISpec:         req.OpenAPISpec,
+		ValidationStatus:    req.ValidationStatus,
 		Status:              "stopped",
+		Source:              source,
+		Workspace:           req.Workspace,
+		Tags:                normalizeTags(req.Tags),
+		Labels:              normalizeLabels(req.Labels),
+	}
+	if err := assignOwner(mock, req.Team, actor); err != nil {
+		return nil, err
+	}
+	if err := storage.DecryptCredentials(mock); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if mock.Port == 0 && mock.UnixSocket == "" {
+		port, err := m.allocatePort(mock)
+		if err != nil {
+			return nil, err
+		}
+		mock.Port = port
+	}
+	if err := validation.Mock(mock); err != nil {
+		return nil, err
+	}
+	if err := m.checkPaths(nil, mock); err != nil {
+		return nil, err
+	}
+
+	// Check if port is already in use
+	if err := m.checkPort("", mock); err != nil {
+		return nil, err
 	}
 
 	m.mocks[id] = mock
@@ -94,29 +261,70 @@ func (m *Manager) Create(req *models.CreateMockAPIRequest) (*models.MockAPI, err
 	}
 
 	mock.Status = "running"
+	return mock, nil
+}
 
-	// Save to storage
-	if err := m.saveToStorage(); err != nil {
-		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+// hasRoute reports whether an HTTP mock with the same port, method and path exists
+func (m *Manager) hasRoute(port int, method, path string) bool {
+	for _, mock := range m.mocks {
+		if mock.Port == port && isHTTP(mock.Protocol) &&
+			strings.EqualFold(mock.Method, method) && mock.Path == path {
+			return true
+		}
 	}
+	return false
+}
 
-	return mock, nil
+// isHTTP reports whether the protocol is served by HTTPServer
+func isHTTP(protocol string) bool {
+	return protocol == models.ProtocolHTTP || protocol == models.ProtocolHTTPS
+}
+
+// ConfigPath returns the config file new mocks are written to
+func (m *Manager) ConfigPath() string {
+	return m.storage.Path()
+}
+
+// StorageWarnings returns the problems found while loading the config
+func (m *Manager) StorageWarnings() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	warnings := m.storage.Warnings()
+	if m.loadErr != nil {
+		warnings = append(warnings, fmt.Sprintf("config failed to load, changes are not saved: %v", m.loadErr))
+	}
+	return warnings
+}
+
+// WatchStorage reloads the mocks whenever the config file(s) change on disk,
+// until stop is closed
+func (m *Manager) WatchStorage(interval time.Duration, stop <-chan struct{}) {
+	m.storage.Watch(interval, stop, func() {
+		log.Printf("Config change detected, reloading mocks")
+		m.Reload()
+	})
 }
 
-// Get retrieves a mock API by ID
+// SaveSpec stores an uploaded OpenAPI document and returns its path
+func (m *Manager) SaveSpec(data []byte) (string, error) {
+	return m.storage.SaveSpec(data)
+}
+
+// Get retrieves a copy of a mock API by ID
 func (m *Manager) Get(id string) (*models.MockAPI, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	mock, exists := m.mocks[id]
 	if !exists {
-		return nil, fmt.Errorf("mock API not found")
+		return nil, ErrNotFound
 	}
 
-	return mock, nil
+	return mock.Clone(), nil
 }
 
-// List returns all mock APIs
+// List returns copies of all mock APIs
 func (m *Manager) List() []*models.MockAPI {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -125,96 +333,184 @@ func (m *Manager) List() []*models.MockAPI {
 	for _, mock := range m.mocks {
 		mocks = append(mocks, mock)
 	}
+	sortMocks(mocks)
 
-	return mocks
+	return cloneMocks(mocks)
 }
 
-// Update updates a mock API
-func (m *Manager) Update(id string, req *models.UpdateMockAPIRequest) (*models.MockAPI, error) {
+// Delete deletes a mock API
+func (m *Manager) Delete(id string, actor *models.Actor) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	mock, exists := m.mocks[id]
 	if !exists {
-		return nil, fmt.Errorf("mock API not found")
+		return ErrNotFound
+	}
+	if err := m.Authorize(actor, mock); err != nil {
+		return err
+	}
+	if err := m.remove(mock, actor); err != nil {
+		return err
 	}
 
-	// Update fields
-	if req.Name != "" {
-		mock.Name = req.Name
+	// Save to storage
+	if err := m.saveToStorage(); err != nil {
+		log.Printf("Warning: Failed to save mocks to storage: %v", err)
 	}
-	if req.Content != "" {
-		mock.Content = req.Content
+
+	return nil
+}
+
+// remove stops and removes a mock without persisting the change
+func (m *Manager) remove(mock *models.MockAPI, actor *models.Actor) error {
+	if err := m.stop(mock); err != nil {
+		return err
 	}
-	if req.Charset != "" {
-		mock.Charset = req.Charset
+
+	delete(m.mocks, mock.ID)
+	delete(m.violations, mock.ID)
+	delete(m.requests, mock.ID)
+	m.record(models.ActionDelete, actor, mock, nil)
+	return nil
+}
+
+// Start starts a stopped mock. Mocks are started again when gomoco restarts.
+func (m *Manager) Start(id string, actor *models.Actor) (*models.MockAPI, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mock, exists := m.mocks[id]
+	if !exists {
+		return nil, ErrNotFound
 	}
-	if req.Path != "" {
-		mock.Path = req.Path
+	if err := m.Authorize(actor, mock); err != nil {
+		return nil, err
 	}
-	if req.Method != "" {
-		mock.Method = req.Method
+	if err := m.start(mock); err != nil {
+		return nil, err
 	}
 
-	// Restart server if running
+	return mock.Clone(), nil
+}
+
+// start starts the server of a mock unless it is running
+func (m *Manager) start(mock *models.MockAPI) error {
 	if mock.Status == "running" {
-		if err := m.stopServer(id); err != nil {
-			return nil, err
-		}
-		if err := m.startServer(mock); err != nil {
-			return nil, err
-		}
+		return nil
 	}
-
-	// Save to storage
-	if err := m.saveToStorage(); err != nil {
-		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+	if err := m.checkPort(mock.ID, mock); err != nil {
+		return err
 	}
-
-	return mock, nil
+	if err := m.startServer(mock); err != nil {
+		return fmt.Errorf("failed to start server: %v", err)
+	}
+	mock.Status = "running"
+	return nil
 }
 
-// Delete deletes a mock API
-func (m *Manager) Delete(id string) error {
+// Stop stops a running mock without deleting it
+func (m *Manager) Stop(id string, actor *models.Actor) (*models.MockAPI, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	mock, exists := m.mocks[id]
 	if !exists {
-		return fmt.Errorf("mock API not found")
+		return nil, ErrNotFound
+	}
+	if err := m.Authorize(actor, mock); err != nil {
+		return nil, err
+	}
+	if err := m.stop(mock); err != nil {
+		return nil, err
 	}
 
-	// Stop server if running
-	if mock.Status == "running" {
-		if err := m.stopServer(id); err != nil {
-			return err
-		}
+	return mock.Clone(), nil
+}
+
+// stop stops the server of a mock if it is running
+func (m *Manager) stop(mock *models.MockAPI) error {
+	if mock.Status != "running" {
+		return nil
+	}
+	if err := m.stopServer(mock.ID); err != nil {
+		return err
 	}
+	mock.Status = "stopped"
+	return nil
+}
 
-	delete(m.mocks, id)
+// Violations returns the requests a mock rejected during contract validation
+func (m *Manager) Violations(id string) ([]Violation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Save to storage
-	if err := m.saveToStorage(); err != nil {
-		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+	if _, exists := m.mocks[id]; !exists {
+		return nil, ErrNotFound
 	}
 
+	return m.violationLog(id).List(), nil
+}
+
+// ClearViolations removes the recorded violations of a mock
+func (m *Manager) ClearViolations(id string, actor *models.Actor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mock, exists := m.mocks[id]
+	if !exists {
+		return ErrNotFound
+	}
+	if err := m.Authorize(actor, mock); err != nil {
+		return err
+	}
+
+	m.violationLog(id).Clear()
 	return nil
 }
 
+// violationLog returns the violation log of a mock, creating it if needed
+func (m *Manager) violationLog(id string) *ViolationLog {
+	violations, exists := m.violations[id]
+	if !exists {
+		violations = &ViolationLog{}
+		m.violations[id] = violations
+	}
+	return violations
+}
+
 // startServer starts a mock server
 func (m *Manager) startServer(mock *models.MockAPI) error {
+	if err := storage.Unresolved(mock); err != nil {
+		return err
+	}
+
 	var server Server
 	var err error
 
 	switch mock.Protocol {
 	case models.ProtocolHTTP, models.ProtocolHTTPS:
-		server, err = NewHTTPServer(mock)
+		var httpServer *HTTPServer
+		httpServer, err = NewHTTPServer(mock, m.bindHost)
+		if err == nil {
+			// Keep violations and requests across restarts of the mock
+			httpServer.violations = m.violationLog(mock.ID)
+			if m.capture {
+				httpServer.requests = m.requestLog(mock.ID)
+			}
+		}
+		server = httpServer
 	case models.ProtocolTCP:
-		server, err = NewTCPServer(mock)
+		var tcpServer *TCPServer
+		tcpServer, err = NewTCPServer(mock, m.bindHost)
+		if err == nil && m.capture {
+			tcpServer.requests = m.requestLog(mock.ID)
+		}
+		server = tcpServer
 	case models.ProtocolFTP:
-		server, err = NewFTPServer(mock)
+		server, err = NewFTPServer(mock, m.bindHost)
 	case models.ProtocolSFTP:
-		server, err = NewSFTPServer(mock)
+		server, err = NewSFTPServer(mock, m.bindHost)
 	default:
 		return fmt.Errorf("unsupported protocol: %s", mock.Protocol)
 	}
@@ -231,6 +527,49 @@ func (m *Manager) startServer(mock *models.MockAPI) error {
 	return nil
 }
 
+// Shutdown stops every running mock server, waiting for their connections
+// to drain until ctx is done. The mocks are not saved as stopped, so they
+// start again with gomoco.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	servers := m.servers
+	m.servers = make(map[string]Server)
+	for id := range servers {
+		if mock, exists := m.mocks[id]; exists {
+			mock.Status = "stopped"
+		}
+	}
+	m.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []string
+	)
+	for id, server := range servers {
+		wg.Add(1)
+		go func(id string, server Server) {
+			defer wg.Done()
+			if err := server.StopContext(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("%s: %v", id, err))
+				mu.Unlock()
+			}
+		}(id, server)
+	}
+
+	wg.Wait()
+	if ctx.Err() != nil {
+		return fmt.Errorf("mock servers did not stop in time: %v", ctx.Err())
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("failed to stop mock servers: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 // stopServer stops a mock server
 func (m *Manager) stopServer(id string) error {
 	server, exists := m.servers[id]
@@ -246,13 +585,26 @@ func (m *Manager) stopServer(id string) error {
 	return nil
 }
 
-// loadFromStorage loads mocks from storage and starts them
+// loadFromStorage loads mocks from storage and starts them. A corrupt config
+// file is replaced by its last good backup.
 func (m *Manager) loadFromStorage() error {
-	mocks, err := m.storage.Load()
+	mocks, err := m.storage.LoadOrRecover()
+	if err == nil {
+		err = checkIDs(mocks)
+	}
 	if err != nil {
 		return err
 	}
 
+	// Mocks added by hand may lack an ID; assign one and write it back
+	if assignIDs(mocks) {
+		defer func() {
+			if err := m.saveToStorage(); err != nil {
+				log.Printf("Warning: Failed to save mocks to storage: %v", err)
+			}
+		}()
+	}
+
 	for _, mock := range mocks {
 		mock.Status = "stopped"
 		m.mocks[mock.ID] = mock
@@ -269,8 +621,12 @@ func (m *Manager) loadFromStorage() error {
 	return nil
 }
 
-// saveToStorage saves all mocks to storage
+// saveToStorage saves all mocks to storage. Nothing is saved while the
+// config fails to load, as that would overwrite it with the mocks in memory.
 func (m *Manager) saveToStorage() error {
+	if m.loadErr != nil {
+		return fmt.Errorf("config failed to load, not overwriting it: %v", m.loadErr)
+	}
 	mocks := make([]*models.MockAPI, 0, len(m.mocks))
 	for _, mock := range m.mocks {
 		mocks = append(mocks, mock)
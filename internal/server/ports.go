@@ -0,0 +1,240 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/validation"
+)
+
+// defaultPassivePorts is the passive port range of FTP mocks without one
+const defaultPassivePorts = "50000-50100"
+
+// Default range ports are auto-assigned from
+const (
+	DefaultMinPort = 20000
+	DefaultMaxPort = 29999
+)
+
+// Kinds of port usage
+const (
+	PortReserved   = "reserved"    // held by gomoco itself, e.g. the management API
+	PortMock       = "mock"        // the port a mock listens on
+	PortFTPPassive = "ftp-passive" // the passive data ports of an FTP mock
+)
+
+// PortUsage describes a port or port range in use
+type PortUsage struct {
+	Port        int    `json:"port"`
+	EndPort     int    `json:"end_port,omitempty"` // Last port of a range
+	Host        string `json:"host,omitempty"`     // Bind host of the mock
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+	MockID      string `json:"mock_id,omitempty"`
+	MockName    string `json:"mock_name,omitempty"`
+	Status      string `json:"status,omitempty"`
+}
+
+// PortRange returns the range ports are auto-assigned from
+func (m *Manager) PortRange() (int, int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.minPort, m.maxPort
+}
+
+// Ports lists the reserved ports and the ports and passive ranges of all
+// mocks not listening on a Unix socket, ordered by port
+func (m *Manager) Ports() []PortUsage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usage := []PortUsage{}
+	for port, description := range m.reserved {
+		usage = append(usage, PortUsage{Port: port, Kind: PortReserved, Description: description})
+	}
+	for _, mock := range m.mocks {
+		if mock.UnixSocket != "" {
+			continue
+		}
+		usage = append(usage, PortUsage{
+			Port:     mock.Port,
+			Host:     mock.BindHost,
+			Kind:     PortMock,
+			Protocol: mock.Protocol,
+			MockID:   mock.ID,
+			MockName: mock.Name,
+			Status:   mock.Status,
+		})
+		if low, high, ok := passiveRange(mock); ok {
+			usage = append(usage, PortUsage{
+				Port:     low,
+				EndPort:  high,
+				Kind:     PortFTPPassive,
+				Protocol: mock.Protocol,
+				MockID:   mock.ID,
+				MockName: mock.Name,
+				Status:   mock.Status,
+			})
+		}
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Port != usage[j].Port {
+			return usage[i].Port < usage[j].Port
+		}
+		if usage[i].Kind != usage[j].Kind {
+			return usage[i].Kind > usage[j].Kind
+		}
+		return usage[i].MockName < usage[j].MockName
+	})
+	return usage
+}
+
+// checkPort returns an error if a mock cannot listen on its port or socket
+// next to the running mocks other than exclude. HTTP mocks of the same
+// protocol and bind host share a port as long as their method and path
+// differ. Ports no running mock holds must also be free in the OS.
+func (m *Manager) checkPort(exclude string, mock *models.MockAPI) error {
+	if mock.UnixSocket != "" {
+		return m.checkSocket(exclude, mock)
+	}
+
+	port, host := mock.Port, m.hostOf(mock)
+	if description, ok := m.reserved[port]; ok {
+		return fmt.Errorf("port %d is reserved for the %s", port, description)
+	}
+	low, high, ranged := passiveRange(mock)
+	if ranged {
+		for reserved, description := range m.reserved {
+			if reserved >= low && reserved <= high {
+				return fmt.Errorf("passive port range %d-%d includes port %d of the %s", low, high, reserved, description)
+			}
+		}
+	}
+
+	held := false
+	for _, other := range m.mocks {
+		if other.Status != "running" || other.UnixSocket != "" {
+			continue
+		}
+		otherHost := m.hostOf(other)
+		if other.Port == port && otherHost == host {
+			held = true
+		}
+		if other.ID == exclude {
+			continue
+		}
+
+		if other.Port == port && hostsOverlap(host, otherHost) {
+			if isHTTP(mock.Protocol) && other.Protocol == mock.Protocol && otherHost == host {
+				if strings.EqualFold(other.Method, mock.Method) && other.Path == mock.Path {
+					return fmt.Errorf("%s %s is already defined on port %d", mock.Method, mock.Path, port)
+				}
+				continue
+			}
+			return fmt.Errorf("port %d is already in use by mock %s", port, other.Name)
+		}
+		if otherLow, otherHigh, ok := passiveRange(other); ok {
+			if port >= otherLow && port <= otherHigh {
+				return fmt.Errorf("port %d is in the passive port range %d-%d of mock %s", port, otherLow, otherHigh, other.Name)
+			}
+			// Mocks may share the default range, but not explicit ones
+			if ranged && mock.FTPPassivePortRange != "" && other.FTPPassivePortRange != "" &&
+				low <= otherHigh && otherLow <= high {
+				return fmt.Errorf("passive port range %d-%d overlaps the range %d-%d of mock %s", low, high, otherLow, otherHigh, other.Name)
+			}
+		}
+		if ranged && other.Port >= low && other.Port <= high {
+			return fmt.Errorf("passive port range %d-%d includes port %d of mock %s", low, high, other.Port, other.Name)
+		}
+	}
+
+	if !held {
+		return portFree(host, port)
+	}
+	return nil
+}
+
+// checkSocket returns an error if a mock cannot listen on its Unix socket
+// next to the running mocks other than exclude
+func (m *Manager) checkSocket(exclude string, mock *models.MockAPI) error {
+	for _, other := range m.mocks {
+		if other.ID == exclude || other.Status != "running" || other.UnixSocket != mock.UnixSocket {
+			continue
+		}
+		if isHTTP(mock.Protocol) && other.Protocol == mock.Protocol {
+			if strings.EqualFold(other.Method, mock.Method) && other.Path == mock.Path {
+				return fmt.Errorf("%s %s is already defined on socket %s", mock.Method, mock.Path, mock.UnixSocket)
+			}
+			continue
+		}
+		return fmt.Errorf("socket %s is already in use by mock %s", mock.UnixSocket, other.Name)
+	}
+	return nil
+}
+
+// allocatePort picks the first port of the auto-assign range that no mock
+// is defined on, that is outside every reserved port and passive range and
+// that is free in the OS
+func (m *Manager) allocatePort(mock *models.MockAPI) (int, error) {
+	low, high, ranged := passiveRange(mock)
+	for port := m.minPort; port <= m.maxPort; port++ {
+		if ranged && port >= low && port <= high {
+			continue
+		}
+		if m.portTaken(port) || portFree(m.hostOf(mock), port) != nil {
+			continue
+		}
+		return port, nil
+	}
+	return 0, validation.Field("port", validation.CodeConflict, "no free port between %d and %d", m.minPort, m.maxPort)
+}
+
+// portTaken reports whether a port is reserved or used by any mock, running
+// or not
+func (m *Manager) portTaken(port int) bool {
+	if _, ok := m.reserved[port]; ok {
+		return true
+	}
+	for _, mock := range m.mocks {
+		if mock.Port == port && mock.UnixSocket == "" {
+			return true
+		}
+		if low, high, ok := passiveRange(mock); ok && port >= low && port <= high {
+			return true
+		}
+	}
+	return false
+}
+
+// passiveRange returns the passive port range of an FTP mock in passive
+// mode
+func passiveRange(mock *models.MockAPI) (int, int, bool) {
+	if mock.Protocol != models.ProtocolFTP || (mock.FTPMode != "" && mock.FTPMode != models.FTPModePassive) {
+		return 0, 0, false
+	}
+	value := mock.FTPPassivePortRange
+	if value == "" {
+		value = defaultPassivePorts
+	}
+	low, high, err := validation.PortRange(value)
+	if err != nil {
+		return 0, 0, false
+	}
+	return low, high, true
+}
+
+// portFree returns an error unless a TCP port can be listened on
+func portFree(host string, port int) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("port %d is not available: %v", port, err)
+	}
+	listener.Close()
+	return nil
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// Query selects, orders and pages mocks. Zero fields match everything.
+type Query struct {
+	Workspace string
+	Protocol  string
+	Status    string            // running or stopped
+	MinPort   int               // Lowest port, inclusive
+	MaxPort   int               // Highest port, inclusive
+	Tags      []string          // Mocks must have all of these tags
+	Labels    map[string]string // Mocks must have all of these labels
+	Search    string            // Case-insensitive text in the name, path or content
+	Sort      string            // name, port, protocol, status or workspace; prefix "-" to reverse
+	Offset    int
+	Limit     int
+}
+
+// sortKeys compares mocks by a sort field
+var sortKeys = map[string]func(a, b *models.MockAPI) int{
+	"name":      func(a, b *models.MockAPI) int { return strings.Compare(a.Name, b.Name) },
+	"port":      func(a, b *models.MockAPI) int { return a.Port - b.Port },
+	"protocol":  func(a, b *models.MockAPI) int { return strings.Compare(a.Protocol, b.Protocol) },
+	"status":    func(a, b *models.MockAPI) int { return strings.Compare(a.Status, b.Status) },
+	"workspace": func(a, b *models.MockAPI) int { return strings.Compare(a.Workspace, b.Workspace) },
+}
+
+// Query returns one page of the mocks matching the query and the total
+// number of matches
+func (m *Manager) Query(q Query) ([]*models.MockAPI, int, error) {
+	field := strings.TrimPrefix(q.Sort, "-")
+	compare, known := sortKeys[field]
+	if q.Sort != "" && !known {
+		return nil, 0, fmt.Errorf("unsupported sort field: %s", field)
+	}
+	if q.Offset < 0 || q.Limit < 0 {
+		return nil, 0, fmt.Errorf("offset and limit must not be negative")
+	}
+
+	m.mu.RLock()
+	mocks := make([]*models.MockAPI, 0, len(m.mocks))
+	for _, mock := range m.mocks {
+		if q.matches(mock) {
+			mocks = append(mocks, mock.Clone())
+		}
+	}
+	m.mu.RUnlock()
+
+	sortMocks(mocks)
+	if compare != nil {
+		descending := strings.HasPrefix(q.Sort, "-")
+		sort.SliceStable(mocks, func(i, j int) bool {
+			if descending {
+				return compare(mocks[j], mocks[i]) < 0
+			}
+			return compare(mocks[i], mocks[j]) < 0
+		})
+	}
+
+	total := len(mocks)
+	if q.Offset >= total {
+		return []*models.MockAPI{}, total, nil
+	}
+	mocks = mocks[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(mocks) {
+		mocks = mocks[:q.Limit]
+	}
+	return mocks, total, nil
+}
+
+// matches reports whether a mock passes the query's filters
+func (q Query) matches(mock *models.MockAPI) bool {
+	if q.Workspace != "" && mock.Workspace != q.Workspace {
+		return false
+	}
+	if q.Protocol != "" && mock.Protocol != q.Protocol {
+		return false
+	}
+	if q.Status != "" && mock.Status != q.Status {
+		return false
+	}
+	if (q.MinPort > 0 && mock.Port < q.MinPort) || (q.MaxPort > 0 && mock.Port > q.MaxPort) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !hasTag(mock, tag) {
+			return false
+		}
+	}
+	for key, value := range q.Labels {
+		if actual, exists := mock.Labels[key]; !exists || actual != value {
+			return false
+		}
+	}
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(mock.Name), search) &&
+			!strings.Contains(strings.ToLower(mock.Path), search) &&
+			!strings.Contains(strings.ToLower(mock.Content), search) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTag reports whether a mock has a tag, ignoring case
+func hasTag(mock *models.MockAPI, tag string) bool {
+	for _, t := range mock.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTags trims tags and drops empty and duplicate ones
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// normalizeLabels trims label keys; empty keys are left for validation
+func normalizeLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	normalized := make(map[string]string, len(labels))
+	for key, value := range labels {
+		normalized[strings.TrimSpace(key)] = value
+	}
+	return normalized
+}
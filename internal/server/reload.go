@@ -0,0 +1,179 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ReloadStatus describes the outcome of the last config reload
+type ReloadStatus struct {
+	Time    time.Time `json:"time"`
+	Error   string    `json:"error,omitempty"`
+	Added   int       `json:"added"`
+	Removed int       `json:"removed"`
+	Changed int       `json:"changed"`
+}
+
+// Reload re-reads the storage and applies the differences to the running
+// mocks: removed mocks are stopped, new ones started and changed ones
+// restarted, while unchanged mocks keep running. If the config cannot be
+// parsed, the running set is left untouched and the error is reported.
+func (m *Manager) Reload() (*ReloadStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &ReloadStatus{Time: time.Now()}
+	m.reloadStatus = status
+
+	mocks, err := m.storage.Load()
+	if err == nil {
+		err = checkIDs(mocks)
+	}
+	if err != nil {
+		status.Error = err.Error()
+		log.Printf("Warning: Failed to reload mocks, keeping the running set: %v", err)
+		return status, err
+	}
+
+	m.loadErr = nil
+
+	// Mocks added by hand may lack an ID; assign one and write it back
+	assigned := assignIDs(mocks)
+
+	loaded := make(map[string]*models.MockAPI, len(mocks))
+	for _, mock := range mocks {
+		applyDefaults(mock)
+		loaded[mock.ID] = mock
+	}
+
+	// Stop removed and changed mocks first, so ports can move between mocks
+	var toStart []*models.MockAPI
+	for id, current := range m.mocks {
+		next, exists := loaded[id]
+		if exists && sameDefinition(current, next) {
+			// Only the file a mock lives in, or how it is written, may have changed
+			current.Source = next.Source
+			current.Placeholders = next.Placeholders
+			continue
+		}
+
+		if current.Status == "running" {
+			if err := m.stopServer(id); err != nil {
+				log.Printf("Warning: Failed to stop mock %s (%s): %v", current.Name, id, err)
+			}
+		}
+		delete(m.mocks, id)
+
+		if exists {
+			status.Changed++
+			toStart = append(toStart, next)
+			m.record(models.ActionReload, models.SystemActor, current, next)
+		} else {
+			status.Removed++
+			delete(m.violations, id)
+			m.record(models.ActionReload, models.SystemActor, current, nil)
+			log.Printf("Removed mock: %s (port %d)", current.Name, current.Port)
+		}
+	}
+	for id, next := range loaded {
+		if _, exists := m.mocks[id]; !exists && !containsMock(toStart, next) {
+			status.Added++
+			toStart = append(toStart, next)
+			m.record(models.ActionReload, models.SystemActor, nil, next)
+		}
+	}
+
+	for _, mock := range toStart {
+		mock.Status = "stopped"
+		m.mocks[mock.ID] = mock
+
+		if err := m.startServer(mock); err != nil {
+			log.Printf("Warning: Failed to start mock %s (%s): %v", mock.Name, mock.ID, err)
+			continue
+		}
+		mock.Status = "running"
+		log.Printf("Reloaded and started mock: %s (port %d)", mock.Name, mock.Port)
+	}
+
+	if assigned {
+		if err := m.saveToStorage(); err != nil {
+			log.Printf("Warning: Failed to save mocks to storage: %v", err)
+		}
+	}
+
+	log.Printf("Reloaded mocks: %d added, %d removed, %d changed", status.Added, status.Removed, status.Changed)
+	return status, nil
+}
+
+// ReloadStatus returns the outcome of the last reload, or nil if the
+// config has not been reloaded yet
+func (m *Manager) ReloadStatus() *ReloadStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.reloadStatus
+}
+
+// checkIDs rejects configs in which two mocks share an ID
+func checkIDs(mocks []*models.MockAPI) error {
+	seen := make(map[string]bool, len(mocks))
+	for _, mock := range mocks {
+		if mock.ID == "" {
+			continue
+		}
+		if seen[mock.ID] {
+			return fmt.Errorf("duplicate mock id %q", mock.ID)
+		}
+		seen[mock.ID] = true
+	}
+	return nil
+}
+
+// assignIDs gives every mock without an ID a new one
+func assignIDs(mocks []*models.MockAPI) bool {
+	assigned := false
+	for _, mock := range mocks {
+		if mock.ID == "" {
+			mock.ID = uuid.New().String()
+			assigned = true
+		}
+	}
+	return assigned
+}
+
+// applyDefaults fills in the protocol settings the servers would default,
+// so that definitions can be compared with running mocks
+func applyDefaults(mock *models.MockAPI) {
+	switch mock.Protocol {
+	case models.ProtocolFTP:
+		applyFTPDefaults(mock)
+	case models.ProtocolSFTP:
+		applySFTPDefaults(mock)
+	}
+}
+
+// sameDefinition reports whether two mocks have the same configuration,
+// ignoring runtime state and the placeholders the values were written as
+func sameDefinition(a, b *models.MockAPI) bool {
+	x, y := *a, *b
+	x.Status, y.Status = "", ""
+	x.Source, y.Source = "", ""
+	x.Placeholders, y.Placeholders = nil, nil
+	return reflect.DeepEqual(x, y)
+}
+
+// containsMock reports whether list contains mock
+func containsMock(list []*models.MockAPI, mock *models.MockAPI) bool {
+	for _, candidate := range list {
+		if candidate == mock {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRequests is the number of received requests kept per mock
+const maxRequests = 500
+
+// maxRequestBody is the number of request body bytes kept per request
+const maxRequestBody = 64 * 1024
+
+// Request is a request received by an HTTP or TCP mock. TCP requests only
+// have a time, remote address and body.
+type Request struct {
+	Time       time.Time   `json:"time"`
+	RemoteAddr string      `json:"remote_addr"`
+	Method     string      `json:"method,omitempty"`
+	Path       string      `json:"path,omitempty"`
+	Query      string      `json:"query,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// RequestLog keeps the most recent requests received by a mock
+type RequestLog struct {
+	mu      sync.Mutex
+	entries []Request
+}
+
+// Add records a request, dropping the oldest entry when full
+func (l *RequestLog) Add(r Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(r.Body) > maxRequestBody {
+		r.Body = r.Body[:maxRequestBody]
+	}
+	if len(l.entries) >= maxRequests {
+		l.entries = l.entries[1:]
+	}
+	l.entries = append(l.entries, r)
+}
+
+// List returns the recorded requests in the order they were received
+func (l *RequestLog) List() []Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := make([]Request, len(l.entries))
+	copy(list, l.entries)
+	return list
+}
+
+// Clear removes all recorded requests
+func (l *RequestLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
+
+// errNoCapture is returned for the requests of a manager that does not
+// capture them
+var errNoCapture = errors.New("requests are not captured, see Options.CaptureRequests")
+
+// Requests returns the requests an HTTP or TCP mock received, oldest first
+func (m *Manager) Requests(id string) ([]Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.mocks[id]; !exists {
+		return nil, ErrNotFound
+	}
+	if !m.capture {
+		return nil, errNoCapture
+	}
+	return m.requestLog(id).List(), nil
+}
+
+// ClearRequests removes the recorded requests of a mock
+func (m *Manager) ClearRequests(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.mocks[id]; !exists {
+		return ErrNotFound
+	}
+	if !m.capture {
+		return errNoCapture
+	}
+	m.requestLog(id).Clear()
+	return nil
+}
+
+// requestLog returns the request log of a mock, creating it if needed
+func (m *Manager) requestLog(id string) *RequestLog {
+	requests, exists := m.requests[id]
+	if !exists {
+		requests = &RequestLog{}
+		m.requests[id] = requests
+	}
+	return requests
+}
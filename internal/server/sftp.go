@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -11,8 +12,9 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
 
-	"gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/models"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
@@ -21,23 +23,19 @@ import (
 // SFTPServer represents an SFTP server
 type SFTPServer struct {
 	mock     *models.MockAPI
+	address  string
 	listener net.Listener
 	running  bool
 	stopChan chan struct{}
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{} // Active client connections
+	wg       sync.WaitGroup
 }
 
-// NewSFTPServer creates a new SFTP server
-func NewSFTPServer(mock *models.MockAPI) (*SFTPServer, error) {
-	// Set default values
-	if mock.SFTPRootDir == "" {
-		mock.SFTPRootDir = filepath.Join("sftp_data", fmt.Sprintf("port_%d", mock.Port))
-	}
-	if mock.SFTPUser == "" {
-		mock.SFTPUser = "admin"
-	}
-	if mock.SFTPPass == "" {
-		mock.SFTPPass = "admin"
-	}
+// NewSFTPServer creates a new SFTP server listening on the mock's bind
+// host, or on defaultHost if it has none
+func NewSFTPServer(mock *models.MockAPI, defaultHost string) (*SFTPServer, error) {
+	applySFTPDefaults(mock)
 
 	// Create SFTP root directory if it doesn't exist
 	if err := os.MkdirAll(mock.SFTPRootDir, 0755); err != nil {
@@ -50,12 +48,32 @@ func NewSFTPServer(mock *models.MockAPI) (*SFTPServer, error) {
 		hostKeyPath = filepath.Join("sftp_keys", fmt.Sprintf("host_key_%d", mock.Port))
 	}
 
+	if defaultHost == "" {
+		defaultHost = "0.0.0.0"
+	}
+	_, address := listenAddress(mock, defaultHost)
+
 	return &SFTPServer{
 		mock:     mock,
+		address:  address,
 		stopChan: make(chan struct{}),
+		conns:    make(map[net.Conn]struct{}),
 	}, nil
 }
 
+// applySFTPDefaults fills in the SFTP settings left empty
+func applySFTPDefaults(mock *models.MockAPI) {
+	if mock.SFTPRootDir == "" {
+		mock.SFTPRootDir = filepath.Join("sftp_data", fmt.Sprintf("port_%d", mock.Port))
+	}
+	if mock.SFTPUser == "" {
+		mock.SFTPUser = "admin"
+	}
+	if mock.SFTPPass == "" {
+		mock.SFTPPass = "admin"
+	}
+}
+
 // Start starts the SFTP server
 func (s *SFTPServer) Start() error {
 	// Configure SSH server
@@ -76,18 +94,20 @@ func (s *SFTPServer) Start() error {
 	config.AddHostKey(hostKey)
 
 	// Start listening
-	listener, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", s.mock.Port))
+	listener, err := net.Listen("tcp", s.address)
 	if err != nil {
-		return fmt.Errorf("failed to listen on port %d: %v", s.mock.Port, err)
+		return fmt.Errorf("failed to listen on %s: %v", s.address, err)
 	}
 	s.listener = listener
 	s.running = true
 
-	log.Printf("Starting SFTP server on port %d (root: %s, user: %s)",
-		s.mock.Port, s.mock.SFTPRootDir, s.mock.SFTPUser)
+	log.Printf("Starting SFTP server on %s (root: %s, user: %s)",
+		s.address, s.mock.SFTPRootDir, s.mock.SFTPUser)
 
 	// Accept connections
+	s.wg.Add(1)
 	go func() {
+		defer s.wg.Done()
 		for {
 			select {
 			case <-s.stopChan:
@@ -101,6 +121,11 @@ func (s *SFTPServer) Start() error {
 					continue
 				}
 
+				if !s.track(conn) {
+					conn.Close()
+					return
+				}
+				s.wg.Add(1)
 				go s.handleConnection(conn, config)
 			}
 		}
@@ -109,15 +134,50 @@ func (s *SFTPServer) Start() error {
 	return nil
 }
 
-// Stop stops the SFTP server
+// Stop stops the SFTP server, closing the active sessions
 func (s *SFTPServer) Stop() error {
-	if s.listener != nil {
-		log.Printf("Stopping SFTP server on port %d", s.mock.Port)
-		s.running = false
-		close(s.stopChan)
-		return s.listener.Close()
+	if s.listener == nil {
+		return nil
 	}
-	return nil
+	log.Printf("Stopping SFTP server on %s", s.address)
+	s.running = false
+	close(s.stopChan)
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// track records an active connection, so Stop can close it. It returns
+// false once the server is stopping.
+func (s *SFTPServer) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stopChan:
+		return false
+	default:
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+// untrack forgets a closed connection
+func (s *SFTPServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// StopContext stops the SFTP server like Stop, returning once ctx is done
+func (s *SFTPServer) StopContext(ctx context.Context) error {
+	return stopWithin(ctx, s.Stop)
 }
 
 // IsRunning returns whether the SFTP server is running
@@ -127,6 +187,8 @@ func (s *SFTPServer) IsRunning() bool {
 
 // handleConnection handles a single SSH connection
 func (s *SFTPServer) handleConnection(conn net.Conn, config *ssh.ServerConfig) {
+	defer s.wg.Done()
+	defer s.untrack(conn)
 	defer conn.Close()
 
 	// Perform SSH handshake
@@ -1,33 +1,47 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"sync"
-	"gomoco/internal/models"
-	"gomoco/internal/utils"
+	"time"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/utils"
 )
 
 // TCPServer represents a TCP mock server
 type TCPServer struct {
 	mock     *models.MockAPI
+	network  string
+	address  string
 	listener net.Listener
+	requests *RequestLog // nil unless requests are captured
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{} // Active client connections
 	wg       sync.WaitGroup
 	stopChan chan struct{}
 }
 
-// NewTCPServer creates a new TCP server
-func NewTCPServer(mock *models.MockAPI) (*TCPServer, error) {
+// NewTCPServer creates a new TCP server listening on the mock's Unix socket
+// or bind host, or on defaultHost if it has neither
+func NewTCPServer(mock *models.MockAPI, defaultHost string) (*TCPServer, error) {
+	network, address := listenAddress(mock, defaultHost)
 	return &TCPServer{
 		mock:     mock,
+		network:  network,
+		address:  address,
+		conns:    make(map[net.Conn]struct{}),
 		stopChan: make(chan struct{}),
 	}, nil
 }
 
 // Start starts the TCP server
 func (s *TCPServer) Start() error {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.mock.Port))
+	listener, err := listen(s.network, s.address)
 	if err != nil {
 		return fmt.Errorf("failed to start TCP server: %v", err)
 	}
@@ -55,11 +69,15 @@ func (s *TCPServer) acceptConnections() {
 				case <-s.stopChan:
 					return
 				default:
-					fmt.Printf("TCP accept error on port %d: %v\n", s.mock.Port, err)
+					fmt.Printf("TCP accept error on %s: %v\n", s.address, err)
 					continue
 				}
 			}
 
+			if !s.track(conn) {
+				conn.Close()
+				return
+			}
 			s.wg.Add(1)
 			go s.handleConnection(conn)
 		}
@@ -69,14 +87,25 @@ func (s *TCPServer) acceptConnections() {
 // handleConnection handles a single TCP connection
 func (s *TCPServer) handleConnection(conn net.Conn) {
 	defer s.wg.Done()
+	defer s.untrack(conn)
 	defer conn.Close()
 
 	// Read incoming data (optional, for logging)
 	buf := make([]byte, 1024)
-	_, err := conn.Read(buf)
+	n, err := conn.Read(buf)
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return // closed by Stop
+	}
 	if err != nil && err != io.EOF {
 		fmt.Printf("TCP read error: %v\n", err)
 	}
+	if s.requests != nil {
+		s.requests.Add(Request{
+			Time:       time.Now(),
+			RemoteAddr: conn.RemoteAddr().String(),
+			Body:       buf[:n],
+		})
+	}
 
 	// Convert content to appropriate charset
 	content, err := utils.ConvertCharset(s.mock.Content, s.mock.Charset)
@@ -87,22 +116,87 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 
 	// Send response
 	_, err = conn.Write(content)
-	if err != nil {
+	if err != nil && !errors.Is(err, net.ErrClosed) {
 		fmt.Printf("TCP write error: %v\n", err)
 	}
 }
 
-// Stop stops the TCP server
+// Stop stops the TCP server, closing the active connections
 func (s *TCPServer) Stop() error {
-	if s.listener == nil {
+	if s.listener == nil || !s.shutdown() {
+		return nil
+	}
+	s.closeConns()
+	s.wg.Wait()
+	return nil
+}
+
+// StopContext stops accepting connections and lets the active ones finish
+// until ctx is done, then closes them
+func (s *TCPServer) StopContext(ctx context.Context) error {
+	if s.listener == nil || !s.shutdown() {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
 		return nil
+	case <-ctx.Done():
 	}
+	s.closeConns()
+	<-done
+	return ctx.Err()
+}
 
+// shutdown stops accepting connections. It returns false if the server was
+// already stopping.
+func (s *TCPServer) shutdown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stopChan:
+		return false
+	default:
+	}
 	close(s.stopChan)
 	s.listener.Close()
-	s.wg.Wait()
+	return true
+}
 
-	return nil
+// closeConns ends pending reads and closes the active connections
+func (s *TCPServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+		conn.Close()
+	}
+}
+
+// track records an active connection, so it can be closed on stop. It
+// returns false once the server is stopping.
+func (s *TCPServer) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stopChan:
+		return false
+	default:
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+// untrack forgets a closed connection
+func (s *TCPServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
 }
 
 // IsRunning checks if the server is running
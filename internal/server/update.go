@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/storage"
+	"github.com/Bahtya/Gomoco/internal/validation"
+)
+
+// ErrNotFound is returned when a mock does not exist
+var ErrNotFound = errors.New("mock API not found")
+
+// ErrInvalid is matched by the errors of invalid mock definitions
+var ErrInvalid = validation.ErrInvalid
+
+// Replace replaces the whole definition of a mock. The ID, status and
+// source of the mock cannot change; credentials sent back redacted keep
+// their stored value.
+func (m *Manager) Replace(id string, def *models.MockAPI, actor *models.Actor) (*models.MockAPI, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mock, exists := m.mocks[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	if def.ID != "" && def.ID != id {
+		return nil, fmt.Errorf("%w: id %s does not match the mock being replaced", ErrInvalid, def.ID)
+	}
+
+	next := def.Clone()
+	next.Placeholders = mock.Clone().Placeholders
+	return m.apply(mock, next, actor)
+}
+
+// Patch applies a JSON merge patch (RFC 7396) to the definition of a mock:
+// fields in the patch replace those of the mock, and null removes them
+func (m *Manager) Patch(id string, patch []byte, actor *models.Actor) (*models.MockAPI, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mock, exists := m.mocks[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	var changes interface{}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if _, ok := changes.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%w: a merge patch must be a JSON object", ErrInvalid)
+	}
+
+	current, err := json.Marshal(mock)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := json.Unmarshal(current, &document); err != nil {
+		return nil, err
+	}
+	patched, err := json.Marshal(mergePatch(document, changes))
+	if err != nil {
+		return nil, err
+	}
+
+	next := &models.MockAPI{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(next); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if next.ID != id {
+		return nil, fmt.Errorf("%w: the id of a mock cannot change", ErrInvalid)
+	}
+	next.Placeholders = mock.Clone().Placeholders
+	return m.apply(mock, next, actor)
+}
+
+// mergePatch applies a JSON merge patch to a decoded JSON document
+func mergePatch(document, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{})
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = mergePatch(object[key], value)
+	}
+	return object
+}
+
+// apply validates the new version of a mock and swaps it in. A running mock
+// is restarted with the new version; if that fails, the old version is
+// started again and an error returned. m.mu must be held.
+func (m *Manager) apply(mock, next *models.MockAPI, actor *models.Actor) (*models.MockAPI, error) {
+	if err := m.Authorize(actor, mock); err != nil {
+		return nil, err
+	}
+
+	// Runtime state is not part of the definition
+	next.ID = mock.ID
+	next.Status = mock.Status
+	next.Source = mock.Source
+	if next.FTPPass == models.RedactedValue {
+		next.FTPPass = mock.FTPPass
+	}
+	if next.SFTPPass == models.RedactedValue {
+		next.SFTPPass = mock.SFTPPass
+	}
+	next.Tags = normalizeTags(next.Tags)
+	next.Labels = normalizeLabels(next.Labels)
+
+	if err := checkOwnerChange(mock, next, actor); err != nil {
+		return nil, err
+	}
+	if err := storage.DecryptCredentials(next); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if next.Port == 0 && next.UnixSocket == "" {
+		port, err := m.allocatePort(next)
+		if err != nil {
+			return nil, err
+		}
+		next.Port = port
+	}
+	if err := validation.Mock(next); err != nil {
+		return nil, err
+	}
+	if err := m.checkPaths(mock, next); err != nil {
+		return nil, err
+	}
+
+	before := mock.Clone()
+	if mock.Status == "running" {
+		if err := m.checkPort(mock.ID, next); err != nil {
+			return nil, err
+		}
+		if err := m.stopServer(mock.ID); err != nil {
+			return nil, err
+		}
+		*mock = *next
+		if err := m.startServer(mock); err != nil {
+			*mock = *before
+			if restartErr := m.startServer(mock); restartErr != nil {
+				log.Printf("Warning: Failed to restart previous version of mock %s: %v", mock.Name, restartErr)
+				mock.Status = "stopped"
+			}
+			return nil, fmt.Errorf("failed to start server, kept the previous version: %v", err)
+		}
+	} else {
+		*mock = *next
+	}
+	m.record(models.ActionUpdate, actor, before, mock)
+
+	// Save to storage
+	if err := m.saveToStorage(); err != nil {
+		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+	}
+
+	return mock.Clone(), nil
+}
+
+// Validate checks a mock definition without applying it, including whether
+// its port is free. A definition with the ID of an existing mock is checked
+// as a replacement of that mock.
+func (m *Manager) Validate(def *models.MockAPI) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mock := def.Clone()
+	if current, exists := m.mocks[def.ID]; exists {
+		if mock.FTPPass == models.RedactedValue {
+			mock.FTPPass = current.FTPPass
+		}
+		if mock.SFTPPass == models.RedactedValue {
+			mock.SFTPPass = current.SFTPPass
+		}
+	}
+	mock.Tags = normalizeTags(mock.Tags)
+	mock.Labels = normalizeLabels(mock.Labels)
+
+	if err := storage.DecryptCredentials(mock); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if mock.Port == 0 && mock.UnixSocket == "" {
+		port, err := m.allocatePort(mock)
+		if err != nil {
+			return err
+		}
+		mock.Port = port
+	}
+	if err := validation.Mock(mock); err != nil {
+		return err
+	}
+	if err := m.checkPaths(m.mocks[def.ID], mock); err != nil {
+		return err
+	}
+	if err := m.checkPort(def.ID, mock); err != nil {
+		return validation.Field("port", validation.CodeConflict, "%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/openapi"
+)
+
+// maxViolations is the number of violations kept per mock
+const maxViolations = 500
+
+// maxViolationBody is the number of request body bytes kept per violation
+const maxViolationBody = 4096
+
+// Violation is a request rejected by OpenAPI contract validation
+type Violation struct {
+	Time       time.Time                 `json:"time"`
+	RemoteAddr string                    `json:"remote_addr"`
+	UserAgent  string                    `json:"user_agent,omitempty"`
+	Method     string                    `json:"method"`
+	Path       string                    `json:"path"`
+	Query      string                    `json:"query,omitempty"`
+	Body       string                    `json:"body,omitempty"`
+	Errors     []openapi.ValidationError `json:"errors"`
+}
+
+// ViolationLog keeps the most recent contract violations of a mock
+type ViolationLog struct {
+	mu      sync.Mutex
+	entries []Violation
+}
+
+// Add records a violation, dropping the oldest entry when full
+func (l *ViolationLog) Add(v Violation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(v.Body) > maxViolationBody {
+		v.Body = v.Body[:maxViolationBody]
+	}
+	if len(l.entries) >= maxViolations {
+		l.entries = l.entries[1:]
+	}
+	l.entries = append(l.entries, v)
+}
+
+// List returns the recorded violations, newest first
+func (l *ViolationLog) List() []Violation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := make([]Violation, len(l.entries))
+	for i, v := range l.entries {
+		list[len(l.entries)-1-i] = v
+	}
+	return list
+}
+
+// Clear removes all recorded violations
+func (l *ViolationLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
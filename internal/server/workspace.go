@@ -0,0 +1,180 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// Workspace summarizes a group of mocks
+type Workspace struct {
+	Name    string `json:"name"`
+	Mocks   int    `json:"mocks"`
+	Running int    `json:"running"`
+}
+
+// sortMocks orders mocks by workspace, name and ID, so listings are stable
+func sortMocks(mocks []*models.MockAPI) {
+	sort.Slice(mocks, func(i, j int) bool {
+		if mocks[i].Workspace != mocks[j].Workspace {
+			return mocks[i].Workspace < mocks[j].Workspace
+		}
+		if mocks[i].Name != mocks[j].Name {
+			return mocks[i].Name < mocks[j].Name
+		}
+		return mocks[i].ID < mocks[j].ID
+	})
+}
+
+// cloneMocks returns copies of mocks, so callers never see the state the
+// manager changes under its lock
+func cloneMocks(mocks []*models.MockAPI) []*models.MockAPI {
+	clones := make([]*models.MockAPI, len(mocks))
+	for i, mock := range mocks {
+		clones[i] = mock.Clone()
+	}
+	return clones
+}
+
+// Workspaces returns the workspaces that contain at least one mock, by name
+func (m *Manager) Workspaces() []Workspace {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	index := make(map[string]int)
+	workspaces := []Workspace{}
+	for _, mock := range m.mocks {
+		if mock.Workspace == "" {
+			continue
+		}
+		i, exists := index[mock.Workspace]
+		if !exists {
+			i = len(workspaces)
+			index[mock.Workspace] = i
+			workspaces = append(workspaces, Workspace{Name: mock.Workspace})
+		}
+		workspaces[i].Mocks++
+		if mock.Status == "running" {
+			workspaces[i].Running++
+		}
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].Name < workspaces[j].Name
+	})
+	return workspaces
+}
+
+// ListWorkspace returns copies of the mocks of a workspace
+func (m *Manager) ListWorkspace(name string) []*models.MockAPI {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return cloneMocks(m.workspaceMocks(name))
+}
+
+// workspaceMocks returns the mocks of a workspace; m.mu must be held
+func (m *Manager) workspaceMocks(name string) []*models.MockAPI {
+	mocks := []*models.MockAPI{}
+	for _, mock := range m.mocks {
+		if mock.Workspace == name {
+			mocks = append(mocks, mock)
+		}
+	}
+	sortMocks(mocks)
+	return mocks
+}
+
+// authorizeWorkspace returns the mocks of a workspace if the actor may
+// change all of them
+func (m *Manager) authorizeWorkspace(name string, actor *models.Actor) ([]*models.MockAPI, error) {
+	mocks := m.workspaceMocks(name)
+	if len(mocks) == 0 {
+		return nil, fmt.Errorf("workspace %s not found", name)
+	}
+	for _, mock := range mocks {
+		if err := m.Authorize(actor, mock); err != nil {
+			return nil, err
+		}
+	}
+	return mocks, nil
+}
+
+// StartWorkspace starts every stopped mock of a workspace. Mocks that fail
+// to start are reported in the error; the others keep running.
+func (m *Manager) StartWorkspace(name string, actor *models.Actor) ([]*models.MockAPI, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mocks, err := m.authorizeWorkspace(name, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []string
+	for _, mock := range mocks {
+		if err := m.start(mock); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", mock.Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return cloneMocks(mocks), fmt.Errorf("failed to start %d of %d mocks: %s", len(failures), len(mocks), strings.Join(failures, "; "))
+	}
+	return cloneMocks(mocks), nil
+}
+
+// StopWorkspace stops every running mock of a workspace
+func (m *Manager) StopWorkspace(name string, actor *models.Actor) ([]*models.MockAPI, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mocks, err := m.authorizeWorkspace(name, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []string
+	for _, mock := range mocks {
+		if err := m.stop(mock); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", mock.Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return cloneMocks(mocks), fmt.Errorf("failed to stop %d of %d mocks: %s", len(failures), len(mocks), strings.Join(failures, "; "))
+	}
+	return cloneMocks(mocks), nil
+}
+
+// DeleteWorkspace deletes every mock of a workspace and returns them. The
+// actor must be allowed to delete all of them, or none is deleted.
+func (m *Manager) DeleteWorkspace(name string, actor *models.Actor) ([]*models.MockAPI, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mocks, err := m.authorizeWorkspace(name, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]*models.MockAPI, 0, len(mocks))
+	var removeErr error
+	for _, mock := range mocks {
+		if removeErr = m.remove(mock, actor); removeErr != nil {
+			break
+		}
+		deleted = append(deleted, mock)
+	}
+
+	// Save to storage
+	if err := m.saveToStorage(); err != nil {
+		log.Printf("Warning: Failed to save mocks to storage: %v", err)
+	}
+
+	if removeErr != nil {
+		return deleted, fmt.Errorf("failed to delete workspace %s: %v", name, removeErr)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultBackups is the number of backups kept per config file
+const DefaultBackups = 5
+
+// writeFileAtomic replaces a file so that readers and crashes only ever see
+// the old or the new content: the data is written to a temporary file in the
+// same directory, flushed to disk and renamed over the target.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself; not supported on every platform
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// backupPath returns the path of the n-th backup, 1 being the newest
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// rotateBackups shifts existing backups by one and moves the current file
+// content into backup 1. A current file that does not parse is set aside
+// instead, so it never replaces a good backup.
+func rotateBackups(path string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var config MocksConfig
+	if err := yaml.Unmarshal(current, &config); err != nil {
+		corrupt := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+		return writeFileAtomic(corrupt, current, 0644)
+	}
+
+	os.Remove(backupPath(path, keep))
+	for n := keep - 1; n >= 1; n-- {
+		if _, err := os.Stat(backupPath(path, n)); err == nil {
+			if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(backupPath(path, 1), current, 0644)
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// historyFile is the append-only revision log kept next to the config file
+const historyFile = "history.jsonl"
+
+// maxHistoryLine bounds a single revision entry in the log
+const maxHistoryLine = 16 * 1024 * 1024
+
+// historyPath returns the location of the revision log
+func (s *YAMLStorage) historyPath() string {
+	return filepath.Join(filepath.Dir(s.filePath), historyFile)
+}
+
+// AppendRevision appends a revision to the history log and assigns its ID
+func (s *YAMLStorage) AppendRevision(rev *models.Revision) error {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	if s.lastRevision == 0 {
+		revs, err := s.readRevisions()
+		if err != nil {
+			return err
+		}
+		for _, r := range revs {
+			if r.ID > s.lastRevision {
+				s.lastRevision = r.ID
+			}
+		}
+	}
+	rev.ID = s.lastRevision + 1
+
+	redacted, err := redactRevision(rev)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		return fmt.Errorf("failed to marshal revision: %v", err)
+	}
+
+	f, err := os.OpenFile(s.historyPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write history file: %v", err)
+	}
+
+	s.lastRevision = rev.ID
+	return nil
+}
+
+// Revisions returns the recorded revisions, newest first. If mockID is not
+// empty, only the revisions of that mock are returned.
+func (s *YAMLStorage) Revisions(mockID string) ([]*models.Revision, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	revs, err := s.readRevisions()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*models.Revision, 0, len(revs))
+	for i := len(revs) - 1; i >= 0; i-- {
+		if mockID == "" || revs[i].MockID == mockID {
+			list = append(list, revs[i])
+		}
+	}
+	return list, nil
+}
+
+// Revision returns a single revision by ID
+func (s *YAMLStorage) Revision(id int64) (*models.Revision, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	revs, err := s.readRevisions()
+	if err != nil {
+		return nil, err
+	}
+	for _, rev := range revs {
+		if rev.ID == id {
+			return rev, nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d not found", id)
+}
+
+// readRevisions reads the whole revision log in append order. A truncated
+// last line, e.g. after a crash, is skipped.
+func (s *YAMLStorage) readRevisions() ([]*models.Revision, error) {
+	f, err := os.Open(s.historyPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %v", err)
+	}
+	defer f.Close()
+
+	var revs []*models.Revision
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxHistoryLine)
+	for scanner.Scan() {
+		var rev models.Revision
+		if err := json.Unmarshal(scanner.Bytes(), &rev); err != nil {
+			continue
+		}
+		revs = append(revs, &rev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %v", err)
+	}
+	return revs, nil
+}
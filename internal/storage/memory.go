@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// MemoryStorage keeps mocks and revisions in memory only, e.g. for mocks
+// embedded in tests
+type MemoryStorage struct {
+	memoryRevisions
+
+	mu    sync.Mutex
+	mocks []*models.MockAPI
+}
+
+// NewMemoryStorage creates an empty in-memory storage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+// Load returns copies of the saved mocks
+func (s *MemoryStorage) Load() ([]*models.MockAPI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mocks := make([]*models.MockAPI, len(s.mocks))
+	for i, mock := range s.mocks {
+		mocks[i] = mock.Clone()
+	}
+	return mocks, nil
+}
+
+// LoadOrRecover loads the mocks like Load
+func (s *MemoryStorage) LoadOrRecover() ([]*models.MockAPI, error) {
+	return s.Load()
+}
+
+// Save keeps copies of the mocks
+func (s *MemoryStorage) Save(mocks []*models.MockAPI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mocks = make([]*models.MockAPI, len(mocks))
+	for i, mock := range mocks {
+		s.mocks[i] = mock.Clone()
+	}
+	return nil
+}
+
+// Path describes the storage
+func (s *MemoryStorage) Path() string {
+	return "memory"
+}
+
+// SourcePath accepts only the default source
+func (s *MemoryStorage) SourcePath(name string) (string, error) {
+	if name != "" {
+		return "", fmt.Errorf("config sources are not supported by the memory storage")
+	}
+	return "", nil
+}
+
+// Warnings returns nothing, as nothing is loaded
+func (s *MemoryStorage) Warnings() []string {
+	return nil
+}
+
+// Watch returns at once, as nothing else changes the mocks
+func (s *MemoryStorage) Watch(interval time.Duration, stop <-chan struct{}, onChange func()) {
+}
+
+// SaveSpec rejects OpenAPI documents, which have no place to be written to
+func (s *MemoryStorage) SaveSpec(data []byte) (string, error) {
+	return "", fmt.Errorf("the memory storage cannot store OpenAPI documents")
+}
+
+// memoryRevisions keeps revisions in memory
+type memoryRevisions struct {
+	revisionsMu sync.Mutex
+	revisions   []*models.Revision
+}
+
+// AppendRevision records a revision in its at rest form, like the YAML
+// and SQLite history, and assigns its ID
+func (r *memoryRevisions) AppendRevision(rev *models.Revision) error {
+	redacted, err := redactRevision(rev)
+	if err != nil {
+		return err
+	}
+
+	r.revisionsMu.Lock()
+	defer r.revisionsMu.Unlock()
+
+	rev.ID = int64(len(r.revisions) + 1)
+	redacted.ID = rev.ID
+	r.revisions = append(r.revisions, redacted)
+	return nil
+}
+
+// Revisions returns the revisions of a mock, or of all mocks if mockID is
+// empty, newest first
+func (r *memoryRevisions) Revisions(mockID string) ([]*models.Revision, error) {
+	r.revisionsMu.Lock()
+	defer r.revisionsMu.Unlock()
+
+	revs := []*models.Revision{}
+	for i := len(r.revisions) - 1; i >= 0; i-- {
+		if mockID == "" || r.revisions[i].MockID == mockID {
+			revs = append(revs, r.revisions[i])
+		}
+	}
+	return revs, nil
+}
+
+// Revision returns a single revision by ID
+func (r *memoryRevisions) Revision(id int64) (*models.Revision, error) {
+	r.revisionsMu.Lock()
+	defer r.revisionsMu.Unlock()
+
+	if id < 1 || id > int64(len(r.revisions)) {
+		return nil, fmt.Errorf("revision %d not found", id)
+	}
+	return r.revisions[id-1], nil
+}
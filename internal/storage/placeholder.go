@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// placeholderPattern matches ${ENV_VAR} and ${file:/path} placeholders, and
+// the escaped form $${...} that stands for the text ${...} itself
+var placeholderPattern = regexp.MustCompile(`\$?\$\{(file:[^}]+|[A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ResolvePlaceholders replaces the ${ENV_VAR} and ${file:/path} placeholders
+// in the string fields of a mock loaded from storage with their values, and
+// decrypts encrypted credentials. The original form of each field is kept in
+// Placeholders, so that saving writes it back instead of the secret. A field
+// that cannot be resolved keeps its placeholder and is reported by
+// Unresolved; the other fields are still resolved.
+//
+// Only definitions read from storage may be resolved: values sent through
+// the API are taken literally.
+func ResolvePlaceholders(mock *models.MockAPI) error {
+	var problems []string
+	walkStrings(mock, func(field string, value *string) {
+		if !isEncryptedCredential(field, *value) && !placeholderPattern.MatchString(*value) {
+			return
+		}
+		if mock.Placeholders == nil {
+			mock.Placeholders = make(map[string]string)
+		}
+		mock.Placeholders[field] = *value
+		resolved, err := resolveValue(field, *value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("field %s: %v", field, err))
+			return
+		}
+		*value = resolved
+	})
+	if len(problems) > 0 {
+		return fmt.Errorf("mock %q: %s", mock.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// resolveLoaded resolves the placeholders of the mocks read from storage and
+// returns the mocks that could not be fully resolved as warnings. Such mocks
+// are still returned, so that saving writes them back unchanged. Encrypted
+// credentials without a master key fail the whole load instead, as the key
+// is missing for every mock.
+func resolveLoaded(mocks []*models.MockAPI) ([]string, error) {
+	if currentKey() == nil {
+		for _, mock := range mocks {
+			var encrypted bool
+			walkStrings(mock, func(field string, value *string) {
+				encrypted = encrypted || isEncryptedCredential(field, *value)
+			})
+			if encrypted {
+				return nil, fmt.Errorf("mock %q has encrypted credentials but no master key is configured (-master-key-file or GOMOCO_MASTER_KEY)", mock.Name)
+			}
+		}
+	}
+
+	var warnings []string
+	for _, mock := range mocks {
+		if err := ResolvePlaceholders(mock); err != nil {
+			warning := err.Error()
+			if mock.Source != "" {
+				warning = mock.Source + ": " + warning
+			}
+			log.Printf("Warning: %s; the mock is not started", warning)
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings, nil
+}
+
+// Unresolved returns an error naming the fields of a mock that still hold a
+// placeholder which failed to resolve on load
+func Unresolved(mock *models.MockAPI) error {
+	var fields []string
+	walkStrings(mock, func(field string, value *string) {
+		if template, exists := mock.Placeholders[field]; exists && template == *value {
+			fields = append(fields, field)
+		}
+	})
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Strings(fields)
+	return fmt.Errorf("unresolved placeholders in %s; fix them in the config and reload, or set the fields", strings.Join(fields, ", "))
+}
+
+// DecryptCredentials decrypts the encrypted credentials of a definition
+// sent through the API, such as one taken from an export. Placeholders are
+// left as they are.
+func DecryptCredentials(mock *models.MockAPI) error {
+	var decryptErr error
+	walkStrings(mock, func(field string, value *string) {
+		if decryptErr != nil || !isEncryptedCredential(field, *value) {
+			return
+		}
+		plaintext, err := decryptValue(*value)
+		if err != nil {
+			decryptErr = fmt.Errorf("field %s: %v", field, err)
+			return
+		}
+		*value = plaintext
+	})
+	return decryptErr
+}
+
+// UnescapePlaceholders turns the escaped $${...} form in the string fields
+// of an exported mock back into the literal text, without resolving the
+// placeholders
+func UnescapePlaceholders(mock *models.MockAPI) {
+	walkStrings(mock, func(field string, value *string) {
+		*value = placeholderPattern.ReplaceAllStringFunc(*value, func(match string) string {
+			return strings.TrimPrefix(match, "$")
+		})
+	})
+}
+
+// resolveValue decrypts an encrypted credential or expands the placeholders
+// of a value
+func resolveValue(field, template string) (string, error) {
+	if isEncryptedCredential(field, template) {
+		return decryptValue(template)
+	}
+	return expandPlaceholders(template)
+}
+
+// expandPlaceholders resolves every placeholder in a string
+func expandPlaceholders(template string) (string, error) {
+	var expandErr error
+	resolved := placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		name := match[2 : len(match)-1]
+		if path, isFile := strings.CutPrefix(name, "file:"); isFile {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				expandErr = fmt.Errorf("failed to read %s: %v", path, err)
+				return ""
+			}
+			// Secret files usually end with a newline
+			return strings.TrimRight(string(data), "\r\n")
+		}
+
+		value, exists := os.LookupEnv(name)
+		if !exists {
+			expandErr = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	return resolved, expandErr
+}
+
+// escapePlaceholders escapes the text of a value that would otherwise be
+// read back as a placeholder
+func escapePlaceholders(value string) string {
+	return placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		return "$" + match
+	})
+}
+
+// AtRest returns the form of a mock that is written to storage: every field
+// that still holds the value of its placeholder is reverted to the
+// placeholder, plaintext credentials are encrypted if a master key is set,
+// and any other text that looks like a placeholder is escaped
+func AtRest(mock *models.MockAPI) (*models.MockAPI, error) {
+	key := currentKey()
+	saved := mock.Clone()
+	var saveErr error
+	walkStrings(saved, func(field string, value *string) {
+		if template, exists := mock.Placeholders[field]; exists {
+			// A field changed since it was loaded is saved as it is now
+			if template == *value {
+				return
+			}
+			if resolved, err := resolveValue(field, template); err == nil && resolved == *value {
+				*value = template
+				return
+			}
+		}
+		if credentialFields[field] && key != nil && *value != "" {
+			encrypted, err := encryptValue(key, *value)
+			if err != nil && saveErr == nil {
+				saveErr = fmt.Errorf("failed to encrypt credentials of mock %q: %v", mock.Name, err)
+			}
+			*value = encrypted
+			return
+		}
+		*value = escapePlaceholders(*value)
+	})
+	if saveErr != nil {
+		return nil, saveErr
+	}
+	return saved, nil
+}
+
+// redactRevision converts both versions in a revision to their at rest form,
+// so that secrets do not end up in the history either
+func redactRevision(rev *models.Revision) (*models.Revision, error) {
+	redacted := *rev
+	var err error
+	if rev.Before != nil {
+		if redacted.Before, err = AtRest(rev.Before); err != nil {
+			return nil, err
+		}
+	}
+	if rev.After != nil {
+		if redacted.After, err = AtRest(rev.After); err != nil {
+			return nil, err
+		}
+	}
+	if redacted.Before != rev.Before || redacted.After != rev.After {
+		redacted.Changes = models.Diff(redacted.Before, redacted.After)
+	}
+	return &redacted, nil
+}
+
+// walkStrings calls fn for every string field of a mock, including the
+// values of string maps such as Headers, named by their JSON field name
+func walkStrings(mock *models.MockAPI, fn func(field string, value *string)) {
+	v := reflect.ValueOf(mock).Elem()
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || name == "id" || name == "status" || name == "source" {
+			continue
+		}
+
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.String:
+			fn(name, field.Addr().Interface().(*string))
+		case field.Kind() == reflect.Map && field.Type().Elem().Kind() == reflect.String:
+			for _, key := range field.MapKeys() {
+				value := field.MapIndex(key).String()
+				fn(name+"."+key.String(), &value)
+				field.SetMapIndex(key, reflect.ValueOf(value))
+			}
+		}
+	}
+}
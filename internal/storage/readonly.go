@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// ReadOnlyStorage loads mocks from another storage but never writes to it.
+// Changes and revisions are kept in memory only.
+type ReadOnlyStorage struct {
+	Storage
+	revisions memoryRevisions
+}
+
+// NewReadOnlyStorage wraps a storage so that nothing is written to it
+func NewReadOnlyStorage(store Storage) *ReadOnlyStorage {
+	return &ReadOnlyStorage{Storage: store}
+}
+
+// LoadOrRecover loads the mocks without restoring backups, which would
+// rewrite the config
+func (s *ReadOnlyStorage) LoadOrRecover() ([]*models.MockAPI, error) {
+	return s.Storage.Load()
+}
+
+// Save discards the mocks
+func (s *ReadOnlyStorage) Save(mocks []*models.MockAPI) error {
+	return nil
+}
+
+// SaveSpec rejects OpenAPI documents, which would be written next to the
+// config
+func (s *ReadOnlyStorage) SaveSpec(data []byte) (string, error) {
+	return "", fmt.Errorf("storage is read-only")
+}
+
+// AppendRevision records a revision in memory and assigns its ID
+func (s *ReadOnlyStorage) AppendRevision(rev *models.Revision) error {
+	return s.revisions.AppendRevision(rev)
+}
+
+// Revisions returns the revisions of a mock, or of all mocks if mockID is
+// empty, newest first
+func (s *ReadOnlyStorage) Revisions(mockID string) ([]*models.Revision, error) {
+	return s.revisions.Revisions(mockID)
+}
+
+// Revision returns a single revision by ID
+func (s *ReadOnlyStorage) Revision(id int64) (*models.Revision, error) {
+	return s.revisions.Revision(id)
+}
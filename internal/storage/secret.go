@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// encryptedPrefix marks a credential encrypted with the master key
+const encryptedPrefix = "enc:v1:"
+
+// credentialFields are the fields encrypted at rest, by JSON field name
+var credentialFields = map[string]bool{
+	"ftp_pass":  true,
+	"sftp_pass": true,
+}
+
+var (
+	masterKeyMu sync.RWMutex
+	masterKey   []byte
+)
+
+// SetMasterKey sets the key credentials are encrypted with. The key is
+// derived from the given material, which should be a long random value.
+func SetMasterKey(material []byte) error {
+	material = []byte(strings.TrimSpace(string(material)))
+	if len(material) < 16 {
+		return fmt.Errorf("master key is too short (at least 16 characters required)")
+	}
+
+	sum := sha256.Sum256(material)
+	masterKeyMu.Lock()
+	defer masterKeyMu.Unlock()
+	masterKey = sum[:]
+	return nil
+}
+
+// currentKey returns the master key, or nil if none is configured
+func currentKey() []byte {
+	masterKeyMu.RLock()
+	defer masterKeyMu.RUnlock()
+	return masterKey
+}
+
+// isEncryptedCredential reports whether a field is a credential encrypted
+// with the master key
+func isEncryptedCredential(field, value string) bool {
+	return credentialFields[field] && strings.HasPrefix(value, encryptedPrefix)
+}
+
+// encryptValue encrypts a credential with AES-GCM. The nonce is derived from
+// the value, so saving an unchanged credential produces unchanged output.
+func encryptValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(plaintext))
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue decrypts a credential written by encryptValue
+func decryptValue(value string) (string, error) {
+	key := currentKey()
+	if key == nil {
+		return "", fmt.Errorf("value is encrypted but no master key is configured")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value (wrong master key?)")
+	}
+	return string(plaintext), nil
+}
+
+// newGCM creates an AES-256-GCM cipher
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,465 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// DefaultDBPath is the SQLite database used when no location is configured
+var DefaultDBPath = filepath.Join(configDir, "gomoco.db")
+
+// sqliteSchema creates the tables on first use
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS mocks (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	port       INTEGER NOT NULL,
+	protocol   TEXT NOT NULL,
+	definition TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS mocks_port ON mocks (port);
+
+CREATE TABLE IF NOT EXISTS revisions (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	mock_id TEXT NOT NULL,
+	time    TIMESTAMP NOT NULL,
+	data    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS revisions_mock_id ON revisions (mock_id);
+
+CREATE TABLE IF NOT EXISTS requests (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	time    INTEGER NOT NULL,
+	actor   TEXT NOT NULL,
+	action  TEXT NOT NULL,
+	mock_id TEXT NOT NULL,
+	data    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS requests_time ON requests (time);
+CREATE INDEX IF NOT EXISTS requests_mock_id ON requests (mock_id);
+`
+
+// SQLiteStorage persists mock APIs in an embedded SQLite database. Each mock
+// is a row, so saving only writes the mocks that actually changed.
+type SQLiteStorage struct {
+	mu   sync.Mutex
+	db   *sql.DB
+	path string
+	rows map[string]string // stored definition of each mock as last seen
+
+	warnings []string // mocks whose placeholders failed to resolve on the last load
+
+	dataVersion int64 // PRAGMA data_version as last seen, to detect outside writes
+}
+
+// NewSQLiteStorage opens or creates a SQLite database
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+	if path == "" {
+		path = DefaultDBPath
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %v", path, err)
+	}
+	// A single connection keeps PRAGMA data_version meaningful
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database %s: %v", path, err)
+	}
+
+	return &SQLiteStorage{
+		db:   db,
+		path: path,
+		rows: make(map[string]string),
+	}, nil
+}
+
+// Close closes the database
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// Load loads all mock APIs from the database, ordered by port and name, and
+// resolves their placeholders
+func (s *SQLiteStorage) Load() ([]*models.MockAPI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT id, definition FROM mocks ORDER BY port, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mocks: %v", err)
+	}
+	defer rows.Close()
+
+	mocks := []*models.MockAPI{}
+	stored := make(map[string]string)
+	for rows.Next() {
+		var id, definition string
+		if err := rows.Scan(&id, &definition); err != nil {
+			return nil, fmt.Errorf("failed to read mock: %v", err)
+		}
+		var mock models.MockAPI
+		if err := json.Unmarshal([]byte(definition), &mock); err != nil {
+			return nil, fmt.Errorf("failed to parse mock %s: %v", id, err)
+		}
+		mock.ID = id
+		mocks = append(mocks, &mock)
+		stored[id] = definition
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query mocks: %v", err)
+	}
+
+	warnings, err := resolveLoaded(mocks)
+	if err != nil {
+		return nil, err
+	}
+	s.rows = stored
+	s.warnings = warnings
+	s.dataVersion, _ = s.readDataVersion()
+	return mocks, nil
+}
+
+// LoadOrRecover loads all mock APIs. SQLite recovers from interrupted
+// writes itself, so this is the same as Load.
+func (s *SQLiteStorage) LoadOrRecover() ([]*models.MockAPI, error) {
+	return s.Load()
+}
+
+// Save writes the mocks that changed since the last load or save and
+// deletes the ones that are gone, in a single transaction
+func (s *SQLiteStorage) Save(mocks []*models.MockAPI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	definitions := make(map[string]string, len(mocks))
+	for _, mock := range mocks {
+		definition, err := marshalDefinition(mock)
+		if err != nil {
+			return err
+		}
+		definitions[mock.ID] = definition
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, mock := range mocks {
+		definition := definitions[mock.ID]
+		if s.rows[mock.ID] == definition {
+			continue
+		}
+		_, err := tx.Exec(`INSERT INTO mocks (id, name, port, protocol, definition, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET name = excluded.name, port = excluded.port,
+				protocol = excluded.protocol, definition = excluded.definition,
+				updated_at = excluded.updated_at`,
+			mock.ID, mock.Name, mock.Port, mock.Protocol, definition, now.Format(time.RFC3339Nano))
+		if err != nil {
+			return fmt.Errorf("failed to save mock %s: %v", mock.ID, err)
+		}
+	}
+	for id := range s.rows {
+		if _, exists := definitions[id]; exists {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM mocks WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete mock %s: %v", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	s.rows = definitions
+	s.dataVersion, _ = s.readDataVersion()
+	return nil
+}
+
+// marshalDefinition encodes the at rest form of a mock, without runtime state
+func marshalDefinition(mock *models.MockAPI) (string, error) {
+	saved, err := AtRest(mock)
+	if err != nil {
+		return "", err
+	}
+	definition := *saved
+	definition.Status = ""
+	definition.Source = ""
+	data, err := json.Marshal(&definition)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal mock %s: %v", mock.ID, err)
+	}
+	return string(data), nil
+}
+
+// Path returns the database file
+func (s *SQLiteStorage) Path() string {
+	return s.path
+}
+
+// SourcePath returns an empty source; all mocks live in the same database
+func (s *SQLiteStorage) SourcePath(name string) (string, error) {
+	if name != "" {
+		return "", fmt.Errorf("config sources are not supported with the SQLite storage")
+	}
+	return "", nil
+}
+
+// Warnings returns the mocks whose placeholders could not be resolved;
+// other problems with the database fail the load
+func (s *SQLiteStorage) Warnings() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.warnings...)
+}
+
+// Watch polls the database and calls onChange whenever another process,
+// such as the sqlite3 shell, committed a change. Returns when stop is closed.
+func (s *SQLiteStorage) Watch(interval time.Duration, stop <-chan struct{}, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if s.changed() {
+				onChange()
+			}
+		}
+	}
+}
+
+// changed reports whether the database was written by another connection
+// since the last check
+func (s *SQLiteStorage) changed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, err := s.readDataVersion()
+	if err != nil || version == s.dataVersion {
+		return false
+	}
+	s.dataVersion = version
+	return true
+}
+
+// readDataVersion returns a counter that changes whenever another
+// connection commits to the database
+func (s *SQLiteStorage) readDataVersion() (int64, error) {
+	var version int64
+	err := s.db.QueryRow(`PRAGMA data_version`).Scan(&version)
+	return version, err
+}
+
+// SaveSpec stores an OpenAPI document in the specs directory next to the database
+func (s *SQLiteStorage) SaveSpec(data []byte) (string, error) {
+	return saveSpec(filepath.Dir(s.path), data)
+}
+
+// AppendRevision records a revision and assigns its ID
+func (s *SQLiteStorage) AppendRevision(rev *models.Revision) error {
+	redacted, err := redactRevision(rev)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		return fmt.Errorf("failed to marshal revision: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`INSERT INTO revisions (mock_id, time, data) VALUES (?, ?, ?)`,
+		rev.MockID, rev.Time.UTC().Format(time.RFC3339Nano), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save revision: %v", err)
+	}
+	if rev.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to save revision: %v", err)
+	}
+	s.dataVersion, _ = s.readDataVersion()
+	return nil
+}
+
+// Revisions returns the recorded revisions, newest first. If mockID is not
+// empty, only the revisions of that mock are returned.
+func (s *SQLiteStorage) Revisions(mockID string) ([]*models.Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `SELECT id, data FROM revisions ORDER BY id DESC`
+	args := []interface{}{}
+	if mockID != "" {
+		query = `SELECT id, data FROM revisions WHERE mock_id = ? ORDER BY id DESC`
+		args = append(args, mockID)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %v", err)
+	}
+	defer rows.Close()
+
+	revs := []*models.Revision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %v", err)
+	}
+	return revs, nil
+}
+
+// Revision returns a single revision by ID
+func (s *SQLiteStorage) Revision(id int64) (*models.Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rev, err := scanRevision(s.db.QueryRow(`SELECT id, data FROM revisions WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("revision %d not found", id)
+	}
+	return rev, err
+}
+
+// scanRevision decodes a revision row
+func scanRevision(row interface{ Scan(...interface{}) error }) (*models.Revision, error) {
+	var id int64
+	var data string
+	if err := row.Scan(&id, &data); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read revision: %v", err)
+	}
+
+	var rev models.Revision
+	if err := json.Unmarshal([]byte(data), &rev); err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %v", id, err)
+	}
+	rev.ID = id
+	return &rev, nil
+}
+
+// AuditLog returns an audit log kept in the requests table of the
+// database, so management requests can be queried without reading a file
+func (s *SQLiteStorage) AuditLog() audit.Store {
+	return sqliteAuditLog{s}
+}
+
+// sqliteAuditLog records audited management requests in the database
+type sqliteAuditLog struct {
+	s *SQLiteStorage
+}
+
+// Append records an entry and assigns its ID
+func (l sqliteAuditLog) Append(entry *audit.Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+
+	result, err := l.s.db.Exec(`INSERT INTO requests (time, actor, action, mock_id, data) VALUES (?, ?, ?, ?, ?)`,
+		entry.Time.UnixNano(), entry.Actor.Name, entry.Action, entry.MockID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save audit entry: %v", err)
+	}
+	if entry.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to save audit entry: %v", err)
+	}
+	l.s.dataVersion, _ = l.s.readDataVersion()
+	return nil
+}
+
+// Query returns the entries passing the filter, newest first
+func (l sqliteAuditLog) Query(filter audit.Filter) ([]*audit.Entry, error) {
+	query := `SELECT id, data FROM requests WHERE 1 = 1`
+	args := []interface{}{}
+	if !filter.Since.IsZero() {
+		query += ` AND time >= ?`
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND time < ?`
+		args = append(args, filter.Until.UnixNano())
+	}
+	if filter.Actor != "" {
+		query += ` AND actor = ?`
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		query += ` AND action = ?`
+		args = append(args, filter.Action)
+	}
+	if filter.MockID != "" {
+		query += ` AND mock_id = ?`
+		args = append(args, filter.MockID)
+	}
+	query += ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+
+	rows, err := l.s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []*audit.Entry{}
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to read audit entry: %v", err)
+		}
+		var entry audit.Entry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit entry %d: %v", id, err)
+		}
+		entry.ID = id
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %v", err)
+	}
+	return entries, nil
+}
@@ -1,90 +1,74 @@
 package storage
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sync"
+	"time"
 
-	"gomoco/internal/models"
-
-	"gopkg.in/yaml.v3"
+	"github.com/Bahtya/Gomoco/internal/models"
 )
 
 const (
-	configDir  = "config"
-	configFile = "mocks.yaml"
+	configDir = "config"
+	specDir   = "specs"
 )
 
-// Storage handles persistence of mock APIs
-type Storage struct {
-	mu       sync.RWMutex
-	filePath string
-}
-
-// MocksConfig represents the YAML configuration structure
-type MocksConfig struct {
-	Mocks []*models.MockAPI `yaml:"mocks"`
-}
-
-// NewStorage creates a new storage instance
-func NewStorage() (*Storage, error) {
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %v", err)
-	}
-
-	filePath := filepath.Join(configDir, configFile)
-
-	return &Storage{
-		filePath: filePath,
-	}, nil
-}
-
-// Load loads mock APIs from YAML file
-func (s *Storage) Load() ([]*models.MockAPI, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Check if file exists
-	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
-		return []*models.MockAPI{}, nil
-	}
-
-	// Read file
-	data, err := os.ReadFile(s.filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %v", err)
-	}
-
-	// Parse YAML
-	var config MocksConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %v", err)
-	}
-
-	return config.Mocks, nil
+// Storage persists mock APIs and their revision history. The YAML files
+// are the default backend; SQLite suits installations with many mocks.
+type Storage interface {
+	// Load loads all mock APIs. Any data that cannot be read fails the load.
+	Load() ([]*models.MockAPI, error)
+	// LoadOrRecover loads all mock APIs like Load, falling back to backups
+	// where the backend keeps them
+	LoadOrRecover() ([]*models.MockAPI, error)
+	// Save persists the complete set of mock APIs
+	Save(mocks []*models.MockAPI) error
+
+	// Path describes where mocks are stored
+	Path() string
+	// SourcePath resolves the location a new mock is written to
+	SourcePath(name string) (string, error)
+	// Warnings returns the problems found while loading
+	Warnings() []string
+	// Watch calls onChange whenever the stored mocks are changed by
+	// something other than this storage, until stop is closed
+	Watch(interval time.Duration, stop <-chan struct{}, onChange func())
+
+	// SaveSpec stores an OpenAPI document and returns its path
+	SaveSpec(data []byte) (string, error)
+
+	// AppendRevision records a revision and assigns its ID
+	AppendRevision(rev *models.Revision) error
+	// Revisions returns the revisions of a mock, or of all mocks if mockID
+	// is empty, newest first
+	Revisions(mockID string) ([]*models.Revision, error)
+	// Revision returns a single revision by ID
+	Revision(id int64) (*models.Revision, error)
 }
 
-// Save saves mock APIs to YAML file
-func (s *Storage) Save(mocks []*models.MockAPI) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	config := MocksConfig{
-		Mocks: mocks,
+// saveSpec stores an OpenAPI document in the specs directory below dir.
+// Documents are named by content hash, so uploading the same document twice
+// reuses the file.
+func saveSpec(dir string, data []byte) (string, error) {
+	dir = filepath.Join(dir, specDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create spec directory: %v", err)
 	}
 
-	// Marshal to YAML
-	data, err := yaml.Marshal(&config)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %v", err)
+	ext := ".yaml"
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		ext = ".json"
 	}
+	sum := sha256.Sum256(data)
+	path := filepath.Join(dir, hex.EncodeToString(sum[:8])+ext)
 
-	// Write to file
-	if err := os.WriteFile(s.filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %v", err)
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write spec file: %v", err)
 	}
 
-	return nil
+	return path, nil
 }
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"os"
+	"time"
+)
+
+// Watch polls the config file(s) and calls onChange whenever their content
+// was changed by something other than this storage, e.g. an editor or a git
+// checkout. Files written by Save do not trigger onChange. Watch returns when
+// stop is closed.
+func (s *YAMLStorage) Watch(interval time.Duration, stop <-chan struct{}, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if s.changed() {
+				onChange()
+			}
+		}
+	}
+}
+
+// changed reports whether any config file differs from the content last
+// loaded or saved. The new state is remembered, so each change is reported once.
+func (s *YAMLStorage) changed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := s.configFiles()
+	if err != nil {
+		return false
+	}
+
+	current := make(map[string]string, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				continue
+			}
+			current[file] = ""
+			continue
+		}
+		current[file] = fingerprint(data)
+	}
+
+	changed := len(current) != len(s.stamps)
+	for file, stamp := range current {
+		if previous, exists := s.stamps[file]; !exists || previous != stamp {
+			changed = true
+		}
+	}
+	if changed {
+		s.stamps = current
+	}
+	return changed
+}
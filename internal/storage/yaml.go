@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the file mocks are written to by default
+const configFile = "mocks.yaml"
+
+// DefaultPath is the config file used when no location is configured
+var DefaultPath = filepath.Join(configDir, configFile)
+
+// YAMLStorage persists mock APIs in YAML files. It either uses a single
+// file or every YAML file in a directory, writing each mock back to the file
+// it was loaded from.
+type YAMLStorage struct {
+	mu         sync.RWMutex
+	filePath   string            // file new mocks are written to
+	dir        string            // config directory in directory mode
+	files      map[string]bool   // files loaded or written so far
+	stamps     map[string]string // content fingerprint of each file as last seen
+	backups    int               // number of backups kept per file
+	warnings   []string          // problems found while loading, e.g. recovered backups
+	unresolved []string          // mocks whose placeholders failed to resolve on the last load
+
+	historyMu    sync.Mutex
+	lastRevision int64
+}
+
+// MocksConfig represents the YAML configuration structure
+type MocksConfig struct {
+	Mocks []*models.MockAPI `yaml:"mocks"`
+}
+
+// NewStorage creates a storage backed by a single YAML file
+func NewStorage(filePath string) (*YAMLStorage, error) {
+	if filePath == "" {
+		filePath = DefaultPath
+	}
+
+	// Create config directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	return &YAMLStorage{
+		filePath: filePath,
+		files:    map[string]bool{filePath: true},
+		stamps:   make(map[string]string),
+		backups:  DefaultBackups,
+	}, nil
+}
+
+// NewDirStorage creates a storage that loads every *.yaml file in dir. New
+// mocks are written to mocks.yaml in that directory.
+func NewDirStorage(dir string) (*YAMLStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %v", err)
+	}
+
+	return &YAMLStorage{
+		filePath: filepath.Join(dir, configFile),
+		dir:      dir,
+		files:    make(map[string]bool),
+		stamps:   make(map[string]string),
+		backups:  DefaultBackups,
+	}, nil
+}
+
+// SetBackups sets the number of backups kept per config file; 0 disables backups
+func (s *YAMLStorage) SetBackups(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.backups = n
+}
+
+// Warnings returns the problems found while loading the config, such as a
+// corrupt file that was replaced by its last good backup
+func (s *YAMLStorage) Warnings() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append(append([]string(nil), s.warnings...), s.unresolved...)
+}
+
+// Path returns the file new mocks are written to
+func (s *YAMLStorage) Path() string {
+	return s.filePath
+}
+
+// SourcePath resolves the file a new mock should be written to. In
+// directory mode name is a file name inside the config directory; an empty
+// name selects the default file.
+func (s *YAMLStorage) SourcePath(name string) (string, error) {
+	if name == "" {
+		return s.filePath, nil
+	}
+	if s.dir == "" {
+		if filepath.Clean(name) == filepath.Clean(s.filePath) {
+			return s.filePath, nil
+		}
+		return "", fmt.Errorf("config sources are only supported with a config directory")
+	}
+
+	if filepath.Base(name) != name || !isConfigFile(name) {
+		return "", fmt.Errorf("invalid config source %q (expected a .yaml file name)", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+// configFiles returns the files to load, sorted by name
+func (s *YAMLStorage) configFiles() ([]string, error) {
+	if s.dir == "" {
+		return []string{s.filePath}, nil
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %v", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfigFile(entry.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(s.dir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isConfigFile reports whether a file name has a YAML extension
+func isConfigFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Load loads mock APIs from the YAML file(s) and resolves their
+// placeholders. Any file that cannot be read or parsed fails the whole load;
+// a mock whose placeholders cannot be resolved is only reported as a warning.
+func (s *YAMLStorage) Load() ([]*models.MockAPI, error) {
+	return s.load(false)
+}
+
+// LoadOrRecover loads mock APIs like Load, but replaces a file that cannot
+// be parsed with its newest backup that can. Each recovery is recorded as a
+// warning.
+func (s *YAMLStorage) LoadOrRecover() ([]*models.MockAPI, error) {
+	return s.load(true)
+}
+
+func (s *YAMLStorage) load(fallback bool) ([]*models.MockAPI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Recoveries of earlier loads are reported again if still needed
+	s.warnings = nil
+	files, err := s.configFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	mocks := []*models.MockAPI{}
+	stamps := make(map[string]string, len(files))
+	for _, file := range files {
+		fileMocks, stamp, err := loadFile(file)
+		if err != nil && fallback {
+			// Remember the corrupt content so the watcher does not reload it
+			if data, readErr := os.ReadFile(file); readErr == nil {
+				stamp = fingerprint(data)
+			}
+			fileMocks, err = s.recoverFile(file, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		stamps[file] = stamp
+		for _, mock := range fileMocks {
+			mock.Source = file
+		}
+		mocks = append(mocks, fileMocks...)
+		s.files[file] = true
+	}
+	unresolved, err := resolveLoaded(mocks)
+	if err != nil {
+		return nil, err
+	}
+	s.stamps = stamps
+	s.unresolved = unresolved
+
+	return mocks, nil
+}
+
+// recoverFile loads the newest backup of a file that failed to load
+func (s *YAMLStorage) recoverFile(file string, loadErr error) ([]*models.MockAPI, error) {
+	for n := 1; n <= s.backups; n++ {
+		backup := backupPath(file, n)
+		info, err := os.Stat(backup)
+		if err != nil {
+			continue
+		}
+		mocks, _, err := loadFile(backup)
+		if err != nil {
+			continue
+		}
+
+		warning := fmt.Sprintf("%v; loaded backup %s from %s instead",
+			loadErr, backup, info.ModTime().Format("2006-01-02 15:04:05"))
+		log.Printf("Warning: %s", warning)
+		s.warnings = append(s.warnings, warning)
+		return mocks, nil
+	}
+	return nil, fmt.Errorf("%v (no usable backup found)", loadErr)
+}
+
+// loadFile loads mock APIs from a single YAML file and returns the
+// fingerprint of its content
+func loadFile(path string) ([]*models.MockAPI, string, error) {
+	// Check if file exists
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return []*models.MockAPI{}, "", nil
+	}
+
+	// Read file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	// Parse YAML
+	var config MocksConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	// Drop empty entries such as a trailing "-"
+	mocks := make([]*models.MockAPI, 0, len(config.Mocks))
+	for _, mock := range config.Mocks {
+		if mock != nil {
+			mocks = append(mocks, mock)
+		}
+	}
+
+	return mocks, fingerprint(data), nil
+}
+
+// Save saves the at rest form of mock APIs to the YAML file(s). Every file
+// that held mocks before is rewritten, so mocks deleted from a file
+// disappear from it.
+func (s *YAMLStorage) Save(mocks []*models.MockAPI) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byFile := make(map[string][]*models.MockAPI)
+	for file := range s.files {
+		byFile[file] = []*models.MockAPI{}
+	}
+	for _, mock := range mocks {
+		file := mock.Source
+		if file == "" {
+			file = s.filePath
+		}
+		saved, err := AtRest(mock)
+		if err != nil {
+			return err
+		}
+		byFile[file] = append(byFile[file], saved)
+	}
+
+	for file, fileMocks := range byFile {
+		stamp, err := saveFile(file, fileMocks, s.backups)
+		if err != nil {
+			return err
+		}
+		s.files[file] = true
+		s.stamps[file] = stamp
+	}
+
+	return nil
+}
+
+// saveFile writes mock APIs to a single YAML file, keeping the given number
+// of backups, and returns the fingerprint of the written content
+func saveFile(path string, mocks []*models.MockAPI, backups int) (string, error) {
+	config := MocksConfig{
+		Mocks: mocks,
+	}
+
+	// Marshal to YAML
+	data, err := yaml.Marshal(&config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %v", err)
+	}
+
+	// Leave files without changes untouched
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		return fingerprint(data), nil
+	}
+
+	if err := rotateBackups(path, backups); err != nil {
+		return "", fmt.Errorf("failed to back up config file %s: %v", path, err)
+	}
+
+	// Write to file
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write config file %s: %v", path, err)
+	}
+
+	return fingerprint(data), nil
+}
+
+// fingerprint returns a hash identifying file content
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SaveSpec stores an OpenAPI document in the specs directory next to the
+// config file
+func (s *YAMLStorage) SaveSpec(data []byte) (string, error) {
+	return saveSpec(filepath.Dir(s.filePath), data)
+}
@@ -3,7 +3,7 @@ package utils
 import (
 	"bytes"
 	"io"
-	"gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/models"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
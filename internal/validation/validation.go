@@ -0,0 +1,323 @@
+package validation
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/openapi"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrInvalid is matched by the errors of invalid mock definitions
+var ErrInvalid = errors.New("invalid mock definition")
+
+// Error codes of field errors
+const (
+	CodeRequired    = "required"     // the field must be set
+	CodeInvalid     = "invalid"      // the value is malformed
+	CodeOutOfRange  = "out_of_range" // a number is outside its allowed range
+	CodeUnsupported = "unsupported"  // the value is not one of the allowed ones
+	CodeNotFound    = "not_found"    // a referenced file cannot be read
+	CodeConflict    = "conflict"     // the value clashes with another mock
+)
+
+// FieldError describes a single invalid field, named by its JSON name
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors lists the invalid fields of a mock definition
+type Errors []FieldError
+
+// Error joins the field errors into one message
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return ErrInvalid.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Is makes errors.Is(err, ErrInvalid) true for validation errors
+func (e Errors) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Field returns an error for a single invalid field
+func Field(field, code, format string, args ...interface{}) error {
+	return Errors{{Field: field, Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+// methods are the HTTP methods a mock may answer
+var methods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "OPTIONS": true, "TRACE": true, "CONNECT": true,
+}
+
+// pathParam matches a path template segment such as {id}
+var pathParam = regexp.MustCompile(`^\{[A-Za-z0-9_.-]+\}$`)
+
+// hostName matches a DNS host name such as localhost
+var hostName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,62})(\.[A-Za-z0-9]([A-Za-z0-9-]{0,62}))*$`)
+
+// maxSocketPath is the longest Unix socket path every platform accepts
+const maxSocketPath = 104
+
+// headerName matches a valid HTTP header field name (RFC 7230 token)
+var headerName = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// validator collects the field errors of a definition
+type validator struct {
+	errs Errors
+}
+
+// add records a field error
+func (v *validator) add(field, code, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Mock checks a complete mock definition, including the files it refers
+// to. It returns Errors listing every invalid field, or nil.
+func Mock(mock *models.MockAPI) error {
+	v := &validator{}
+	v.common(mock)
+	switch mock.Protocol {
+	case models.ProtocolHTTP, models.ProtocolHTTPS:
+		v.http(mock)
+	case models.ProtocolFTP:
+		v.ftp(mock)
+	case models.ProtocolSFTP:
+		v.sftp(mock)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// common checks the fields every protocol uses
+func (v *validator) common(mock *models.MockAPI) {
+	if strings.TrimSpace(mock.Name) == "" {
+		v.add("name", CodeRequired, "name is required")
+	}
+	if mock.UnixSocket != "" {
+		switch mock.Protocol {
+		case models.ProtocolHTTP, models.ProtocolHTTPS, models.ProtocolTCP:
+		default:
+			v.add("unix_socket", CodeUnsupported, "only HTTP, HTTPS and TCP mocks can listen on a Unix socket")
+		}
+		if mock.BindHost != "" {
+			v.add("bind_host", CodeConflict, "bind_host and unix_socket cannot be used together")
+		}
+		if len(mock.UnixSocket) > maxSocketPath || strings.ContainsRune(mock.UnixSocket, 0) {
+			v.add("unix_socket", CodeInvalid, "unix_socket must be a file path of at most %d bytes", maxSocketPath)
+		}
+	} else if mock.Port < 1 || mock.Port > 65535 {
+		v.add("port", CodeOutOfRange, "port must be between 1 and 65535")
+	}
+	if mock.BindHost != "" && !validHost(mock.BindHost) {
+		v.add("bind_host", CodeInvalid, "invalid bind host %q, expected an IP address or host name", mock.BindHost)
+	}
+
+	switch mock.Protocol {
+	case models.ProtocolHTTP, models.ProtocolHTTPS, models.ProtocolTCP, models.ProtocolFTP, models.ProtocolSFTP:
+	case "":
+		v.add("protocol", CodeRequired, "protocol is required")
+	default:
+		v.add("protocol", CodeUnsupported, "unsupported protocol %q (http, https, tcp, ftp or sftp)", mock.Protocol)
+	}
+
+	switch mock.Charset {
+	case models.CharsetUTF8, models.CharsetGBK:
+	case "":
+		v.add("charset", CodeRequired, "charset is required")
+	default:
+		v.add("charset", CodeUnsupported, "unsupported charset %q (UTF-8 or GBK)", mock.Charset)
+	}
+
+	if mock.Workspace != "" && !models.ValidWorkspace(mock.Workspace) {
+		v.add("workspace", CodeInvalid, "workspace names consist of letters, digits, '.', '_' and '-'")
+	}
+	for key := range mock.Labels {
+		if strings.TrimSpace(key) == "" {
+			v.add("labels", CodeInvalid, "label keys must not be empty")
+			break
+		}
+	}
+}
+
+// http checks the HTTP and HTTPS fields
+func (v *validator) http(mock *models.MockAPI) {
+	if mock.Path != "" {
+		v.path(mock.Path)
+	}
+	if mock.Method != "" && !methods[strings.ToUpper(mock.Method)] {
+		v.add("method", CodeUnsupported, "unsupported HTTP method %q", mock.Method)
+	}
+	if mock.StatusCode != 0 && (mock.StatusCode < 100 || mock.StatusCode > 599) {
+		v.add("status_code", CodeOutOfRange, "status_code must be between 100 and 599")
+	}
+	if mock.ValidationStatus != 0 && (mock.ValidationStatus < 400 || mock.ValidationStatus > 599) {
+		v.add("validation_status", CodeOutOfRange, "validation_status must be between 400 and 599")
+	}
+	if mock.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(mock.ContentType); err != nil {
+			v.add("content_type", CodeInvalid, "invalid content type %q: %v", mock.ContentType, err)
+		}
+	}
+	names := make([]string, 0, len(mock.Headers))
+	for name := range mock.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := mock.Headers[name]
+		if !headerName.MatchString(name) {
+			v.add("headers."+name, CodeInvalid, "invalid header name %q", name)
+		} else if strings.ContainsAny(value, "\r\n") {
+			v.add("headers."+name, CodeInvalid, "header values must not contain line breaks")
+		}
+	}
+	if mock.OpenAPISpec != "" {
+		v.openAPISpec(mock.OpenAPISpec)
+	}
+
+	if mock.Protocol == models.ProtocolHTTPS {
+		v.certificate(mock.CertFile, mock.KeyFile)
+	}
+}
+
+// path checks the syntax of an HTTP path or path template
+func (v *validator) path(path string) {
+	if !strings.HasPrefix(path, "/") {
+		v.add("path", CodeInvalid, "path must start with '/'")
+		return
+	}
+	if strings.ContainsAny(path, "?#") {
+		v.add("path", CodeInvalid, "path must not contain a query or fragment")
+		return
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.ContainsAny(segment, "{}") && !pathParam.MatchString(segment) {
+			v.add("path", CodeInvalid, "invalid path parameter %q: parameters must span a whole segment, e.g. /users/{id}", segment)
+			return
+		}
+	}
+}
+
+// openAPISpec checks that the contract document loads and that its
+// patterns compile
+func (v *validator) openAPISpec(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		v.add("openapi_spec", CodeNotFound, "failed to read spec file: %v", err)
+		return
+	}
+	if _, err := openapi.Parse(data); err != nil {
+		v.add("openapi_spec", CodeInvalid, "%v", err)
+		return
+	}
+	if err := openapi.CheckPatterns(data); err != nil {
+		v.add("openapi_spec", CodeInvalid, "%v", err)
+	}
+}
+
+// certificate checks that an HTTPS certificate and key form a pair
+func (v *validator) certificate(certFile, keyFile string) {
+	if certFile == "" {
+		v.add("cert_file", CodeRequired, "HTTPS mocks need a certificate file")
+	}
+	if keyFile == "" {
+		v.add("key_file", CodeRequired, "HTTPS mocks need a key file")
+	}
+	if certFile == "" || keyFile == "" {
+		return
+	}
+
+	if _, err := os.Stat(certFile); err != nil {
+		v.add("cert_file", CodeNotFound, "%v", err)
+		return
+	}
+	if _, err := os.Stat(keyFile); err != nil {
+		v.add("key_file", CodeNotFound, "%v", err)
+		return
+	}
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		v.add("cert_file", CodeInvalid, "failed to load certificate and key: %v", err)
+	}
+}
+
+// ftp checks the FTP fields
+func (v *validator) ftp(mock *models.MockAPI) {
+	switch mock.FTPMode {
+	case "", models.FTPModeActive, models.FTPModePassive:
+	default:
+		v.add("ftp_mode", CodeUnsupported, "unsupported FTP mode %q (active or passive)", mock.FTPMode)
+	}
+
+	if mock.FTPPassivePortRange != "" {
+		low, high, err := PortRange(mock.FTPPassivePortRange)
+		if err != nil {
+			v.add("ftp_passive_port_range", CodeInvalid, "%v", err)
+		} else if mock.Port >= low && mock.Port <= high {
+			v.add("ftp_passive_port_range", CodeConflict, "passive port range must not include the control port %d", mock.Port)
+		}
+	}
+}
+
+// sftp checks the SFTP fields
+func (v *validator) sftp(mock *models.MockAPI) {
+	// A missing host key is generated on start
+	if mock.SFTPHostKey != "" {
+		if data, err := os.ReadFile(mock.SFTPHostKey); err == nil {
+			if _, err := ssh.ParsePrivateKey(data); err != nil {
+				v.add("sftp_host_key", CodeInvalid, "failed to parse host key: %v", err)
+			}
+		} else if !os.IsNotExist(err) {
+			v.add("sftp_host_key", CodeNotFound, "%v", err)
+		}
+	}
+	if mock.SFTPPrivateKey != "" {
+		data, err := os.ReadFile(mock.SFTPPrivateKey)
+		if err != nil {
+			v.add("sftp_private_key", CodeNotFound, "%v", err)
+		} else if _, err := ssh.ParsePrivateKey(data); err != nil {
+			v.add("sftp_private_key", CodeInvalid, "failed to parse private key: %v", err)
+		}
+	}
+}
+
+// validHost reports whether a bind host is an IP address or a host name
+func validHost(host string) bool {
+	return net.ParseIP(host) != nil || hostName.MatchString(host)
+}
+
+// PortRange parses a port range such as "50000-50100"
+func PortRange(value string) (int, int, error) {
+	low, high, found := strings.Cut(value, "-")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid port range %q, expected e.g. 50000-50100", value)
+	}
+	min, err1 := strconv.Atoi(strings.TrimSpace(low))
+	max, err2 := strconv.Atoi(strings.TrimSpace(high))
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("invalid port range %q, expected e.g. 50000-50100", value)
+	}
+	if min < 1 || max > 65535 || min > max {
+		return 0, 0, fmt.Errorf("invalid port range %q, ports must be between 1 and 65535 and ascending", value)
+	}
+	return min, max, nil
+}
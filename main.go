@@ -1,20 +1,60 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"embed"
 	"flag"
 	"fmt"
-	"gomoco/internal/api"
-	"gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/api"
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/auth"
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/openapi"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/storage"
+	"github.com/Bahtya/Gomoco/internal/validation"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
 //go:embed web/dist
 var staticFiles embed.FS
 
 var (
-	port    = flag.Int("port", 8080, "API server port")
-	version = flag.Bool("version", false, "Show version information")
+	port      = flag.Int("port", 8080, "API server port")
+	version   = flag.Bool("version", false, "Show version information")
+	config    = flag.String("config", "", "Mocks config file (env GOMOCO_CONFIG, default config/mocks.yaml)")
+	configDir = flag.String("config-dir", "", "Load every *.yaml file in a directory (env GOMOCO_CONFIG_DIR)")
+	backups   = flag.Int("backups", storage.DefaultBackups, "Number of backups kept per config file")
+	backend   = flag.String("storage", "yaml", "Storage backend: yaml or sqlite (env GOMOCO_STORAGE)")
+	dbPath    = flag.String("db", "", "SQLite database file (env GOMOCO_DB, default config/gomoco.db)")
+	masterKey = flag.String("master-key-file", "", "File holding the key credentials are encrypted with (or env GOMOCO_MASTER_KEY)")
+	auditLog  = flag.String("audit-log", "", "Audit log of management operations (default audit.jsonl next to the config, or the database with the SQLite storage)")
+	mockBind  = flag.String("mock-bind", "", "Address mocks without a bind_host listen on, e.g. 127.0.0.1 or ::1 (default all interfaces)")
+	mockPorts = flag.String("mock-ports", fmt.Sprintf("%d-%d", server.DefaultMinPort, server.DefaultMaxPort), "Range ports of mocks created with port 0 are picked from")
+	dataDir   = flag.String("data-dir", ".", "Directory FTP/SFTP root directories and Unix sockets set through the API must be inside (empty allows any)")
+	watch     = flag.Bool("watch", true, "Reload mocks when the config file(s) change")
+	watchRate = flag.Duration("watch-interval", 2*time.Second, "How often to check the config file(s) for changes")
+	drainTime = flag.Duration("shutdown-timeout", 10*time.Second, "How long to wait for connections to drain on shutdown")
+
+	authTokens    = flag.String("auth-tokens", "", "File of name:token API tokens that may use the management API")
+	authPasswords = flag.String("auth-htpasswd", "", "File of name:bcrypt-hash users for basic auth (see htpasswd -nbB)")
+	hashPassword  = flag.Bool("hash-password", false, "Read a password from stdin and print its bcrypt hash for -auth-htpasswd")
+	authRoles     = flag.String("auth-roles", "", "YAML file assigning roles and teams to users and OIDC groups")
+	oidcIssuer    = flag.String("oidc-issuer", "", "OpenID Connect issuer URL whose bearer tokens are accepted")
+	oidcAudience  = flag.String("oidc-audience", "", "Expected audience (client ID) of OIDC tokens")
+	corsOrigins   = flag.String("cors-origins", "", "Comma separated origins web pages may call the management API from, e.g. https://portal.example.com (default any without authentication, none with it)")
+	oidcClaim     = flag.String("oidc-username-claim", "", "OIDC claim holding the user name (default sub)")
+
+	importSpec     = flag.String("import-openapi", "", "Import HTTP mocks from an OpenAPI/Swagger document")
+	importPort     = flag.Int("import-port", 9000, "Port for mocks imported with -import-openapi")
+	importValidate = flag.Bool("import-validate", false, "Validate requests to imported mocks against the document")
 )
 
 const (
@@ -23,12 +63,53 @@ const (
 )
 
 func main() {
-	flag.Parse()
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
+	switch command {
+	case "serve":
+		flag.Usage = usage
+		flag.CommandLine.Parse(args)
+		serve()
+	case "run":
+		os.Exit(runCommand(args))
+	case "validate":
+		os.Exit(validateCommand(args))
+	case "ctl":
+		os.Exit(ctlCommand(args))
+	case "version":
+		printVersion()
+	case "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		usage()
+		os.Exit(2)
+	}
+}
+
+// serve runs gomoco with the management API and web UI, storing changes in
+// the config
+func serve() {
 	// Show version
 	if *version {
-		fmt.Printf("%s v%s\n", appName, appVersion)
-		fmt.Println("A lightweight mock server written in Go")
+		printVersion()
+		return
+	}
+
+	// Hash a password for the basic auth password file
+	if *hashPassword {
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			log.Fatalf("Failed to read password: %v", err)
+		}
+		hash, err := auth.HashPassword(strings.TrimRight(password, "\r\n"))
+		if err != nil {
+			log.Fatalf("Failed to hash password: %v", err)
+		}
+		fmt.Println(hash)
 		return
 	}
 
@@ -36,16 +117,287 @@ func main() {
 	if *port < 1 || *port > 65535 {
 		log.Fatalf("Invalid port number: %d (must be between 1 and 65535)", *port)
 	}
+	minPort, maxPort, err := validation.PortRange(*mockPorts)
+	if err != nil {
+		log.Fatalf("Invalid -mock-ports: %v", err)
+	}
 
-	// Initialize mock server manager
-	manager := server.NewManager()
+	// Credentials are encrypted at rest when a master key is configured
+	if err := loadMasterKey(); err != nil {
+		log.Fatalf("Failed to load master key: %v", err)
+	}
+
+	// Initialize storage and mock server manager
+	store, err := newStorage()
+	if err != nil {
+		log.Fatalf("Failed to create storage: %v", err)
+	}
+	manager := server.NewManager(store, server.Options{
+		BindHost:      *mockBind,
+		MinPort:       minPort,
+		MaxPort:       maxPort,
+		DataDir:       *dataDir,
+		ReservedPorts: map[int]string{*port: "management API"},
+	})
+
+	// Hot reload on config changes
+	stopWatch := make(chan struct{})
+	if *watch {
+		go manager.WatchStorage(*watchRate, stopWatch)
+	}
+
+	// Import mocks from an OpenAPI document
+	if *importSpec != "" {
+		if err := importOpenAPI(manager, *importSpec, *importPort, *importValidate); err != nil {
+			log.Fatalf("Failed to import %s: %v", *importSpec, err)
+		}
+	}
+
+	// Authentication for the management API and web UI
+	authenticators, err := newAuthenticators()
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+	origins, err := parseOrigins(*corsOrigins)
+	if err != nil {
+		log.Fatalf("Invalid -cors-origins: %v", err)
+	}
+	var roles *auth.Roles
+	if *authRoles != "" {
+		if roles, err = auth.LoadRoles(*authRoles); err != nil {
+			log.Fatalf("Failed to configure authentication: %v", err)
+		}
+	}
+
+	// Audit log of management operations, kept in the database with the
+	// SQLite storage unless a file is given
+	var auditLogger audit.Store
+	if db, ok := store.(*storage.SQLiteStorage); ok && *auditLog == "" {
+		auditLogger = db.AuditLog()
+	} else {
+		auditPath := *auditLog
+		if auditPath == "" {
+			auditPath = filepath.Join(filepath.Dir(store.Path()), "audit.jsonl")
+		}
+		if auditLogger, err = audit.Open(auditPath); err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+	}
 
 	// Start API server
-	apiServer := api.NewServer(manager, staticFiles)
+	apiServer := api.NewServer(manager, staticFiles, api.Options{
+		Auth:           authenticators,
+		Roles:          roles,
+		Audit:          auditLogger,
+		Version:        appVersion,
+		AllowedOrigins: origins,
+	})
 
 	addr := fmt.Sprintf(":%d", *port)
 	log.Printf("Starting %s v%s on http://localhost%s", appName, appVersion, addr)
-	if err := apiServer.Run(addr); err != nil {
+
+	waitForShutdown(manager, apiServer, addr, stopWatch)
+}
+
+// waitForShutdown serves the API, if any, until SIGINT or SIGTERM (e.g.
+// docker stop), then stops the config watcher, the API and every mock server
+func waitForShutdown(manager *server.Manager, apiServer *api.Server, addr string, stopWatch chan struct{}) {
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	if apiServer != nil {
+		go func() {
+			errs <- apiServer.Run(addr)
+		}()
+	}
+	select {
+	case err := <-errs:
 		log.Fatal("Failed to start API server:", err)
+	case <-signals.Done():
+	}
+	stop()
+
+	log.Printf("Shutting down, waiting up to %v for connections to drain", *drainTime)
+	if stopWatch != nil {
+		close(stopWatch)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *drainTime)
+	defer cancel()
+	if apiServer != nil {
+		if err := apiServer.Shutdown(ctx); err != nil {
+			log.Printf("Warning: Failed to shut down API server: %v", err)
+		}
+	}
+	if err := manager.Shutdown(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	log.Printf("Stopped")
+}
+
+// newStorage creates the storage from the -storage, -db, -config and
+// -config-dir flags, falling back to the GOMOCO_* environment variables
+func newStorage() (storage.Storage, error) {
+	kind := *backend
+	if env := os.Getenv("GOMOCO_STORAGE"); env != "" && !flagSet("storage") {
+		kind = env
+	}
+	switch kind {
+	case "yaml":
+		return newYAMLStorage()
+	case "sqlite":
+		path := *dbPath
+		if path == "" {
+			path = os.Getenv("GOMOCO_DB")
+		}
+		if *config != "" || *configDir != "" {
+			return nil, fmt.Errorf("-config and -config-dir cannot be used with the SQLite storage")
+		}
+		log.Printf("Using SQLite storage")
+		return storage.NewSQLiteStorage(path)
 	}
+	return nil, fmt.Errorf("unknown storage backend %q (expected yaml or sqlite)", kind)
+}
+
+// newYAMLStorage creates the YAML storage from the -config/-config-dir flags,
+// falling back to the GOMOCO_CONFIG/GOMOCO_CONFIG_DIR environment variables.
+// Either flag overrides both variables.
+func newYAMLStorage() (storage.Storage, error) {
+	file, dir := *config, *configDir
+	if file != "" && dir != "" {
+		return nil, fmt.Errorf("-config and -config-dir cannot be used together")
+	}
+	if file == "" && dir == "" {
+		file, dir = os.Getenv("GOMOCO_CONFIG"), os.Getenv("GOMOCO_CONFIG_DIR")
+		if file != "" && dir != "" {
+			return nil, fmt.Errorf("GOMOCO_CONFIG and GOMOCO_CONFIG_DIR cannot be used together")
+		}
+	}
+	var store *storage.YAMLStorage
+	var err error
+	if dir != "" {
+		log.Printf("Loading mocks from config directory %s", dir)
+		store, err = storage.NewDirStorage(dir)
+	} else {
+		store, err = storage.NewStorage(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	store.SetBackups(*backups)
+	return store, nil
+}
+
+// parseOrigins splits the comma separated -cors-origins flag
+func parseOrigins(value string) ([]string, error) {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return nil, fmt.Errorf("invalid origin %q, expected e.g. https://portal.example.com", origin)
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// newAuthenticators creates the authenticators enabled on the command line.
+// Without any, the management API is open to everyone who can reach it.
+func newAuthenticators() (auth.Chain, error) {
+	var chain auth.Chain
+	if *authTokens != "" {
+		tokens, err := auth.LoadTokens(*authTokens)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, tokens)
+	}
+	if *authPasswords != "" {
+		passwords, err := auth.LoadPasswords(*authPasswords)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, passwords)
+	}
+	if *oidcIssuer != "" {
+		oidc, err := auth.NewOIDC(auth.OIDCOptions{
+			Issuer:        *oidcIssuer,
+			Audience:      *oidcAudience,
+			UsernameClaim: *oidcClaim,
+		})
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, oidc)
+	}
+
+	if len(chain) == 0 {
+		log.Printf("Warning: Authentication is disabled; anyone who can reach port %d can change mocks", *port)
+	}
+	return chain, nil
+}
+
+// loadMasterKey sets the credential encryption key from the
+// -master-key-file flag or the GOMOCO_MASTER_KEY environment variable
+func loadMasterKey() error {
+	var material []byte
+	if *masterKey != "" {
+		data, err := os.ReadFile(*masterKey)
+		if err != nil {
+			return err
+		}
+		material = data
+	} else if env := os.Getenv("GOMOCO_MASTER_KEY"); env != "" {
+		material = []byte(env)
+	} else {
+		return nil
+	}
+
+	if err := storage.SetMasterKey(material); err != nil {
+		return err
+	}
+	log.Printf("Credentials are encrypted at rest")
+	return nil
+}
+
+// flagSet reports whether a flag was given on the command line
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// importOpenAPI creates mocks for every operation of an OpenAPI document
+func importOpenAPI(manager *server.Manager, path string, port int, validate bool) error {
+	doc, err := openapi.Load(path)
+	if err != nil {
+		return err
+	}
+
+	opts := openapi.ImportOptions{Port: port}
+	if validate {
+		if opts.SpecPath, err = filepath.Abs(path); err != nil {
+			return err
+		}
+	}
+
+	reqs, err := doc.BuildMocks(opts)
+	if err != nil {
+		return err
+	}
+
+	mocks, skipped, err := manager.Import(reqs, models.SystemActor, nil)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Imported %d mocks from %s on port %d (%d already existed)", len(mocks), path, port, skipped)
+	return nil
 }
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/audit"
+	"github.com/Bahtya/Gomoco/internal/server"
+)
+
+// ReloadStatus describes the outcome of a config reload
+type ReloadStatus = server.ReloadStatus
+
+// AuditEntry records a single management operation
+type AuditEntry = audit.Entry
+
+// PortUsage describes a port or port range in use
+type PortUsage = server.PortUsage
+
+// User is the caller as seen by the server
+type User struct {
+	Name          string   `json:"name"`
+	Method        string   `json:"method,omitempty"` // token, basic or oidc
+	Role          string   `json:"role,omitempty"`
+	Teams         []string `json:"teams,omitempty"`
+	Authenticated bool     `json:"authenticated"`
+}
+
+// Status describes the config and the last reload of a server
+type Status struct {
+	Config     string        `json:"config"`
+	Mocks      int           `json:"mocks"`
+	LastReload *ReloadStatus `json:"last_reload"`
+	Warnings   []string      `json:"warnings"`
+}
+
+// Ports lists the ports in use and the range free ports are assigned from
+type Ports struct {
+	Ports      []PortUsage `json:"ports"`
+	AutoAssign struct {
+		Min int `json:"min"`
+		Max int `json:"max"`
+	} `json:"auto_assign"`
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Since  time.Time
+	Until  time.Time
+	Actor  string
+	Action string
+	MockID string
+	Limit  int
+}
+
+// WhoAmI returns the authenticated caller
+func (c *Client) WhoAmI(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/auth/whoami"}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Status reports the config location, storage warnings and the outcome
+// of the last reload
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/status"}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Reload makes the server re-read its config
+func (c *Client) Reload(ctx context.Context) (*ReloadStatus, error) {
+	var status ReloadStatus
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/reload"}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Audit queries the audit log, newest first
+func (c *Client) Audit(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	query := url.Values{}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		query.Set("until", filter.Until.Format(time.RFC3339))
+	}
+	if filter.Actor != "" {
+		query.Set("actor", filter.Actor)
+	}
+	if filter.Action != "" {
+		query.Set("action", filter.Action)
+	}
+	if filter.MockID != "" {
+		query.Set("mock_id", filter.MockID)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	var entries []*AuditEntry
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/audit", query: query}, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Ports lists the ports in use and by which mock
+func (c *Client) Ports(ctx context.Context) (*Ports, error) {
+	var ports Ports
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/ports"}, &ports); err != nil {
+		return nil, err
+	}
+	return &ports, nil
+}
@@ -0,0 +1,207 @@
+// Package client is a Go client for the gomoco management API.
+//
+//	c := client.New("http://localhost:8080", client.Options{Token: token})
+//	mock, err := c.CreateMock(ctx, &client.CreateMockAPIRequest{
+//		Name:     "users",
+//		Protocol: "http",
+//		Port:     9090,
+//		Method:   "GET",
+//		Path:     "/users",
+//		Content:  `[]`,
+//	})
+//	if errors.Is(err, client.ErrInvalid) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/validation"
+)
+
+// Errors matched by errors.Is against the errors returned by the client
+var (
+	ErrInvalid      = errors.New("invalid request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+)
+
+// FieldError describes a single invalid field of a mock definition
+type FieldError = validation.FieldError
+
+// Error is an error response of the API, {"error": "..."}
+type Error struct {
+	StatusCode int          `json:"-"`
+	Message    string       `json:"error"`
+	Fields     []FieldError `json:"fields,omitempty"` // Set for invalid mock definitions
+}
+
+// Error returns the message of the response
+func (e *Error) Error() string {
+	return fmt.Sprintf("gomoco: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// Is matches the error against ErrInvalid, ErrUnauthorized, ErrForbidden
+// and ErrNotFound by status code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInvalid:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// Options configures a client
+type Options struct {
+	// HTTPClient sends the requests; nil uses http.DefaultClient
+	HTTPClient *http.Client
+	// Token is sent as "Authorization: Bearer <token>"
+	Token string
+	// Username and Password are sent as basic auth
+	Username string
+	Password string
+	// User names the actor in the change history when authentication is
+	// disabled
+	User string
+	// Reveal asks for clear text credentials in mock definitions
+	Reveal bool
+}
+
+// Client calls the management API of a gomoco server
+type Client struct {
+	baseURL string
+	http    *http.Client
+	opts    Options
+}
+
+// New creates a client for the server at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts Options) *Client {
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    httpClient,
+		opts:    opts,
+	}
+}
+
+// request describes an API call
+type request struct {
+	method      string
+	path        string // below /api
+	query       url.Values
+	body        io.Reader
+	contentType string
+}
+
+// send performs an API call and returns the response if its status is
+// below 400
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	query := r.query
+	if c.opts.Reveal {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("reveal", "true")
+	}
+	target := c.baseURL + "/api" + r.path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, target, r.body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if c.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.opts.Token)
+	} else if c.opts.Username != "" {
+		req.SetBasicAuth(c.opts.Username, c.opts.Password)
+	}
+	if c.opts.User != "" {
+		req.Header.Set("X-Gomoco-User", c.opts.User)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// do performs an API call and decodes the JSON response into out, unless
+// out is nil
+func (c *Client) do(ctx context.Context, r request, out interface{}) error {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// doJSON performs an API call with a JSON body
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, request{
+		method:      method,
+		path:        path,
+		body:        bytes.NewReader(data),
+		contentType: "application/json",
+	}, out)
+}
+
+// decodeError reads an error response
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	return apiErr
+}
+
+// escape escapes a single path segment
+func escape(segment string) string {
+	return url.PathEscape(segment)
+}
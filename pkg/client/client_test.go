@@ -0,0 +1,347 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/api"
+	"github.com/Bahtya/Gomoco/internal/auth"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/storage"
+)
+
+// Tokens of the test server, see newTestServer
+const (
+	adminToken  = "admin-token-0123456789"
+	viewerToken = "viewer-token-0123456789"
+)
+
+// newTestServer serves the management API over an in-memory manager whose
+// mocks listen on localhost. With authentication, adminToken belongs to an
+// admin and viewerToken to a viewer.
+func newTestServer(t *testing.T, authenticate bool) string {
+	t.Helper()
+	manager := server.NewManager(storage.NewMemoryStorage(), server.Options{BindHost: "127.0.0.1"})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		manager.Shutdown(ctx)
+	})
+
+	opts := api.Options{DisableUI: true}
+	if authenticate {
+		dir := t.TempDir()
+		tokens := filepath.Join(dir, "tokens")
+		roles := filepath.Join(dir, "roles.yaml")
+		writeFile(t, tokens, "admin:"+adminToken+"\nviewer:"+viewerToken+"\n")
+		writeFile(t, roles, "default_role: viewer\nusers:\n  token:admin: {role: admin}\n")
+
+		tokenAuth, err := auth.LoadTokens(tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.Roles, err = auth.LoadRoles(roles); err != nil {
+			t.Fatal(err)
+		}
+		opts.Auth = auth.Chain{tokenAuth}
+	}
+
+	ts := httptest.NewServer(api.NewServer(manager, embed.FS{}, opts).Handler())
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// get returns the body of a GET request to a mock
+func get(t *testing.T, port int, path string) string {
+	t.Helper()
+	resp, err := http.Get("http://127.0.0.1:" + strconv.Itoa(port) + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMockCRUD(t *testing.T) {
+	ctx := context.Background()
+	c := New(newTestServer(t, false), Options{User: "tester"})
+
+	mock, err := c.CreateMock(ctx, &CreateMockAPIRequest{
+		Name:      "hello",
+		Protocol:  "http",
+		Charset:   "UTF-8",
+		Method:    "GET",
+		Path:      "/hello",
+		Content:   "hi",
+		Workspace: "demo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mock.ID == "" || mock.Port == 0 || mock.Status != "running" {
+		t.Fatalf("created mock %+v", mock)
+	}
+	if body := get(t, mock.Port, "/hello"); body != "hi" {
+		t.Errorf("mock answered %q, want hi", body)
+	}
+
+	got, err := c.GetMock(ctx, mock.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "hello" || got.Workspace != "demo" {
+		t.Errorf("got mock %+v", got)
+	}
+
+	mocks, total, err := c.ListMocks(ctx, ListOptions{Workspace: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(mocks) != 1 || mocks[0].ID != mock.ID {
+		t.Errorf("listed %d of %d mocks", len(mocks), total)
+	}
+	if _, total, err = c.ListMocks(ctx, ListOptions{Workspace: "other"}); err != nil || total != 0 {
+		t.Errorf("listed %d mocks of another workspace (err %v)", total, err)
+	}
+
+	patched, err := c.PatchMock(ctx, mock.ID, map[string]interface{}{"content": "patched"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Content != "patched" {
+		t.Errorf("patched content %q", patched.Content)
+	}
+	if body := get(t, mock.Port, "/hello"); body != "patched" {
+		t.Errorf("patched mock answered %q", body)
+	}
+
+	def := *patched
+	def.Content = "replaced"
+	replaced, err := c.ReplaceMock(ctx, mock.ID, &def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.Content != "replaced" {
+		t.Errorf("replaced content %q", replaced.Content)
+	}
+
+	if stopped, err := c.StopMock(ctx, mock.ID); err != nil || stopped.Status != "stopped" {
+		t.Fatalf("stop: %+v, %v", stopped, err)
+	}
+	if started, err := c.StartMock(ctx, mock.ID); err != nil || started.Status != "running" {
+		t.Fatalf("start: %+v, %v", started, err)
+	}
+
+	if err := c.DeleteMock(ctx, mock.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetMock(ctx, mock.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get of deleted mock: %v, want ErrNotFound", err)
+	}
+}
+
+// Placeholders sent through the API are literal text, also when a revision
+// of the mock is rolled back
+func TestRollbackKeepsPlaceholders(t *testing.T) {
+	t.Setenv("GOMOCO_TEST_SECRET", "topsecret")
+	ctx := context.Background()
+	c := New(newTestServer(t, false), Options{})
+
+	mock, err := c.CreateMock(ctx, &CreateMockAPIRequest{
+		Name:     "literal",
+		Protocol: "http",
+		Charset:  "UTF-8",
+		Method:   "GET",
+		Path:     "/",
+		Content:  "${GOMOCO_TEST_SECRET}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PatchMock(ctx, mock.ID, map[string]interface{}{"content": "changed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	revs, err := c.Revisions(ctx, mock.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("%d revisions, want 2", len(revs))
+	}
+	restored, err := c.Rollback(ctx, revs[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Content != "${GOMOCO_TEST_SECRET}" {
+		t.Errorf("restored content %q", restored.Content)
+	}
+	if body := get(t, mock.Port, "/"); body != "${GOMOCO_TEST_SECRET}" {
+		t.Errorf("restored mock answered %q", body)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	ctx := context.Background()
+	c := New(newTestServer(t, false), Options{})
+
+	root := t.TempDir()
+	mock, err := c.CreateMock(ctx, &CreateMockAPIRequest{
+		Name:       "files",
+		Protocol:   "ftp",
+		Charset:    "UTF-8",
+		FTPUser:    "user",
+		FTPPass:    "secret",
+		FTPRootDir: root,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploaded, err := c.UploadFile(ctx, mock.ID, "in", "hello.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Size != 5 {
+		t.Errorf("uploaded %d bytes", uploaded.Size)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "in", "hello.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("file on disk: %q, %v", data, err)
+	}
+
+	list, err := c.ListFiles(ctx, mock.ID, "in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Files) != 1 || list.Files[0].Name != "hello.txt" {
+		t.Errorf("listed %+v", list.Files)
+	}
+
+	var buf bytes.Buffer
+	if _, err := c.DownloadFile(ctx, mock.ID, "in/hello.txt", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "hello" {
+		t.Errorf("downloaded %q", buf.String())
+	}
+
+	if err := c.DeleteFile(ctx, mock.ID, "in/hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.DownloadFile(ctx, mock.ID, "in/hello.txt", io.Discard); !errors.Is(err, ErrNotFound) {
+		t.Errorf("download of deleted file: %v, want ErrNotFound", err)
+	}
+	if _, err := c.ListFiles(ctx, mock.ID, ".."); !errors.Is(err, ErrForbidden) {
+		t.Errorf("listing outside the root directory: %v, want ErrForbidden", err)
+	}
+	// Exports hold credentials only when revealed
+	bundle, err := c.Export(ctx, ExportOptions{IDs: []string{mock.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(bundle), "secret") {
+		t.Errorf("export holds the password:\n%s", bundle)
+	}
+	bundle, err = New(c.baseURL, Options{Reveal: true}).Export(ctx, ExportOptions{IDs: []string{mock.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bundle), "ftp_pass: secret") {
+		t.Errorf("revealed export lacks the password:\n%s", bundle)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	url := newTestServer(t, true)
+	admin := New(url, Options{Token: adminToken})
+	viewer := New(url, Options{Token: viewerToken})
+
+	_, err := admin.CreateMock(ctx, &CreateMockAPIRequest{Name: "bad", Protocol: "gopher"})
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("invalid mock: %v, want ErrInvalid", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid mock: %#v", err)
+	}
+	if len(apiErr.Fields) == 0 || apiErr.Fields[0].Field != "protocol" {
+		t.Errorf("invalid mock fields: %+v", apiErr.Fields)
+	}
+
+	if _, err := admin.GetMock(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing mock: %v, want ErrNotFound", err)
+	}
+
+	_, err = viewer.CreateMock(ctx, &CreateMockAPIRequest{Name: "x", Protocol: "http", Charset: "UTF-8", Path: "/x"})
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("create by viewer: %v, want ErrForbidden", err)
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalid) {
+		t.Errorf("create by viewer matches other errors: %v", err)
+	}
+
+	if _, err := New(url, Options{}).ListWorkspaces(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("anonymous request: %v, want ErrUnauthorized", err)
+	}
+	if _, err := New(url, Options{Token: "wrong-token-0123456789"}).ListWorkspaces(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("wrong token: %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	// Answers once the request is canceled
+	blocked := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(blocked)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := New(ts.URL, Options{}).GetMock(ctx, "id")
+		done <- err
+	}()
+	<-blocked
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("canceled request: %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not canceled")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := New(newTestServer(t, false), Options{}).Status(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expired context: %v, want context.DeadlineExceeded", err)
+	}
+}
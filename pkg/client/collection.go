@@ -0,0 +1,140 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatZip  = "zip" // Includes the FTP/SFTP file trees
+)
+
+// Import formats
+const (
+	ImportBundle  = "bundle" // A gomoco export in any format
+	ImportPostman = "postman"
+	ImportHAR     = "har"
+)
+
+// ExportOptions selects the mocks to export. Without IDs or a workspace
+// all mocks are exported.
+type ExportOptions struct {
+	Format    string // yaml (default), json or zip
+	IDs       []string
+	Workspace string
+}
+
+// ImportOptions configures an import of a collection
+type ImportOptions struct {
+	Format     string // bundle (default), postman or har
+	Port       int    // Port of the generated Postman/HAR mocks
+	PortOffset int    // Added to the ports of bundled mocks
+	PortMap    string // Bundled ports to new ports, e.g. "8080:9080,8443:9443"
+	Workspace  string
+}
+
+// OpenAPIImportOptions configures an import of an OpenAPI/Swagger document
+type OpenAPIImportOptions struct {
+	Port       int
+	Protocol   string // http (default) or https
+	CertFile   string
+	KeyFile    string
+	NamePrefix string
+	Validate   bool // Validate requests against the document
+	Workspace  string
+}
+
+// ImportResult lists the mocks created by an import
+type ImportResult struct {
+	Created int        `json:"created"`
+	Skipped int        `json:"skipped"` // Mocks whose route already existed
+	Mocks   []*MockAPI `json:"mocks"`
+}
+
+// Export returns a bundle of mocks, which Import accepts
+func (c *Client) Export(ctx context.Context, opts ExportOptions) ([]byte, error) {
+	query := url.Values{}
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+	if len(opts.IDs) > 0 {
+		query.Set("ids", strings.Join(opts.IDs, ","))
+	}
+	if opts.Workspace != "" {
+		query.Set("workspace", opts.Workspace)
+	}
+
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/export", query: query})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Import creates the mocks of a gomoco bundle, a Postman collection or a
+// HAR file
+func (c *Client) Import(ctx context.Context, data []byte, opts ImportOptions) (*ImportResult, error) {
+	query := url.Values{}
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+	if opts.Port > 0 {
+		query.Set("port", strconv.Itoa(opts.Port))
+	}
+	if opts.PortOffset != 0 {
+		query.Set("port_offset", strconv.Itoa(opts.PortOffset))
+	}
+	if opts.PortMap != "" {
+		query.Set("port_map", opts.PortMap)
+	}
+	if opts.Workspace != "" {
+		query.Set("workspace", opts.Workspace)
+	}
+	return c.importDocument(ctx, "/import", query, data)
+}
+
+// ImportOpenAPI creates an HTTP mock for each operation of an OpenAPI 3 or
+// Swagger 2 document
+func (c *Client) ImportOpenAPI(ctx context.Context, data []byte, opts OpenAPIImportOptions) (*ImportResult, error) {
+	query := url.Values{}
+	query.Set("port", strconv.Itoa(opts.Port))
+	set := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	set("protocol", opts.Protocol)
+	set("cert_file", opts.CertFile)
+	set("key_file", opts.KeyFile)
+	set("name_prefix", opts.NamePrefix)
+	set("workspace", opts.Workspace)
+	if opts.Validate {
+		query.Set("validate", "true")
+	}
+	return c.importDocument(ctx, "/import/openapi", query, data)
+}
+
+// importDocument posts a document to an import endpoint
+func (c *Client) importDocument(ctx context.Context, path string, query url.Values, data []byte) (*ImportResult, error) {
+	var result ImportResult
+	err := c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        path,
+		query:       query,
+		body:        bytes.NewReader(data),
+		contentType: "application/octet-stream",
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
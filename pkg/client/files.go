@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FileInfo describes a file served by an FTP or SFTP mock
+type FileInfo struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	IsDir   bool   `json:"is_dir"`
+	ModTime string `json:"mod_time"`
+	Path    string `json:"path"` // Relative to the root directory
+}
+
+// FileList is the content of a directory served by an FTP or SFTP mock
+type FileList struct {
+	Files       []FileInfo `json:"files"`
+	CurrentPath string     `json:"current_path"`
+	RootDir     string     `json:"root_dir"`
+}
+
+// UploadedFile describes a file written by UploadFile
+type UploadedFile struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Path     string `json:"path"`
+}
+
+// filePath returns the API path of a file served by a mock
+func filePath(id, path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return "/mocks/" + escape(id) + "/files/" + strings.Join(segments, "/")
+}
+
+// ListFiles lists a directory served by an FTP or SFTP mock; an empty dir
+// lists the root
+func (c *Client) ListFiles(ctx context.Context, id, dir string) (*FileList, error) {
+	query := url.Values{}
+	if dir != "" {
+		query.Set("path", dir)
+	}
+	var list FileList
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/mocks/" + escape(id) + "/files", query: query}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// UploadFile writes the content of r as dir/name into the directory served
+// by an FTP or SFTP mock. Files are limited to 100MB.
+func (c *Client) UploadFile(ctx context.Context, id, dir, name string, r io.Reader) (*UploadedFile, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("path", dir); err != nil {
+		return nil, err
+	}
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	var uploaded UploadedFile
+	err = c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/mocks/" + escape(id) + "/files",
+		body:        &body,
+		contentType: form.FormDataContentType(),
+	}, &uploaded)
+	if err != nil {
+		return nil, err
+	}
+	return &uploaded, nil
+}
+
+// DownloadFile copies a file served by an FTP or SFTP mock to w
+func (c *Client) DownloadFile(ctx context.Context, id, path string, w io.Writer) (int64, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: filePath(id, path)})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, resp.Body)
+}
+
+// DeleteFile deletes a file or directory served by an FTP or SFTP mock
+func (c *Client) DeleteFile(ctx context.Context, id, path string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: filePath(id, path)}, nil)
+}
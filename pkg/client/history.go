@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// Revision is a recorded change of a mock
+type Revision = models.Revision
+
+// Revisions returns the revisions of a mock, or of all mocks if mockID is
+// empty, newest first. A limit of 0 returns all revisions.
+func (c *Client) Revisions(ctx context.Context, mockID string, limit int) ([]*Revision, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/revisions"
+	if mockID != "" {
+		path = "/mocks/" + escape(mockID) + "/revisions"
+	}
+
+	var revs []*Revision
+	if err := c.do(ctx, request{method: http.MethodGet, path: path, query: query}, &revs); err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+// Rollback restores a mock to the state recorded by a revision and restarts
+// it
+func (c *Client) Rollback(ctx context.Context, rev int64) (*MockAPI, error) {
+	var mock MockAPI
+	path := "/revisions/" + strconv.FormatInt(rev, 10) + "/rollback"
+	if err := c.do(ctx, request{method: http.MethodPost, path: path}, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/server"
+)
+
+// MockAPI is a mock definition as returned by the API
+type MockAPI = models.MockAPI
+
+// CreateMockAPIRequest defines a new mock
+type CreateMockAPIRequest = models.CreateMockAPIRequest
+
+// Violation is a request rejected by OpenAPI contract validation
+type Violation = server.Violation
+
+// ListOptions filters and pages mock listings. Zero fields match
+// everything.
+type ListOptions struct {
+	Workspace string
+	Protocol  string
+	Status    string // running or stopped
+	Search    string // matched against name, path and content
+	Tags      []string
+	Labels    map[string]string
+	MinPort   int // MinPort and MaxPort select a port range if MaxPort is set
+	MaxPort   int
+	Sort      string // e.g. "port" or "-name"
+	Offset    int
+	Limit     int
+}
+
+// query returns the query parameters of the options
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	set("workspace", o.Workspace)
+	set("protocol", o.Protocol)
+	set("status", o.Status)
+	set("q", o.Search)
+	set("sort", o.Sort)
+	for _, tag := range o.Tags {
+		query.Add("tag", tag)
+	}
+	keys := make([]string, 0, len(o.Labels))
+	for key := range o.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query.Add("label", key+"="+o.Labels[key])
+	}
+	if o.MaxPort > 0 {
+		query.Set("port", fmt.Sprintf("%d-%d", o.MinPort, o.MaxPort))
+	}
+	if o.Offset > 0 {
+		query.Set("offset", strconv.Itoa(o.Offset))
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return query
+}
+
+// ListMocks lists the mocks matching opts and returns them with the total
+// number of matches
+func (c *Client) ListMocks(ctx context.Context, opts ListOptions) ([]*MockAPI, int, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/mocks", query: opts.query()})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var mocks []*MockAPI
+	if err := json.NewDecoder(resp.Body).Decode(&mocks); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode response: %v", err)
+	}
+	total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		total = len(mocks)
+	}
+	return mocks, total, nil
+}
+
+// GetMock returns a mock by ID
+func (c *Client) GetMock(ctx context.Context, id string) (*MockAPI, error) {
+	var mock MockAPI
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/mocks/" + escape(id)}, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+// CreateMock creates and starts a mock. A port of 0 picks a free one.
+func (c *Client) CreateMock(ctx context.Context, req *CreateMockAPIRequest) (*MockAPI, error) {
+	var mock MockAPI
+	if err := c.doJSON(ctx, http.MethodPost, "/mocks", req, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+// ReplaceMock replaces the whole definition of a mock
+func (c *Client) ReplaceMock(ctx context.Context, id string, def *MockAPI) (*MockAPI, error) {
+	var mock MockAPI
+	if err := c.doJSON(ctx, http.MethodPut, "/mocks/"+escape(id), def, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+// PatchMock applies a JSON merge patch to a mock. The patch is sent as is
+// if it is a []byte or json.RawMessage, and marshaled otherwise, e.g.
+//
+//	c.PatchMock(ctx, id, map[string]interface{}{"status_code": 503})
+func (c *Client) PatchMock(ctx context.Context, id string, patch interface{}) (*MockAPI, error) {
+	if data, ok := patch.([]byte); ok {
+		patch = json.RawMessage(data)
+	}
+	var mock MockAPI
+	if err := c.doJSON(ctx, http.MethodPatch, "/mocks/"+escape(id), patch, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+// DeleteMock stops and deletes a mock
+func (c *Client) DeleteMock(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/mocks/" + escape(id)}, nil)
+}
+
+// StartMock starts a stopped mock
+func (c *Client) StartMock(ctx context.Context, id string) (*MockAPI, error) {
+	var mock MockAPI
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/mocks/" + escape(id) + "/start"}, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+// StopMock stops a running mock without deleting it
+func (c *Client) StopMock(ctx context.Context, id string) (*MockAPI, error) {
+	var mock MockAPI
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/mocks/" + escape(id) + "/stop"}, &mock); err != nil {
+		return nil, err
+	}
+	return &mock, nil
+}
+
+// Validation is the outcome of a dry run
+type Validation struct {
+	Valid  bool         `json:"valid"`
+	Error  string       `json:"error,omitempty"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// ValidateMock checks a definition without creating it. Invalid
+// definitions are reported in the result, not as an error.
+func (c *Client) ValidateMock(ctx context.Context, def *MockAPI) (*Validation, error) {
+	var result Validation
+	if err := c.doJSON(ctx, http.MethodPost, "/mocks/validate", def, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Violations lists the requests a mock rejected during contract validation
+func (c *Client) Violations(ctx context.Context, id string) ([]Violation, error) {
+	var violations []Violation
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/mocks/" + escape(id) + "/violations"}, &violations); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
+// ClearViolations removes the recorded violations of a mock
+func (c *Client) ClearViolations(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/mocks/" + escape(id) + "/violations"}, nil)
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Bahtya/Gomoco/internal/server"
+)
+
+// Workspace summarizes a group of mocks
+type Workspace = server.Workspace
+
+// ListWorkspaces lists the workspaces with their number of mocks
+func (c *Client) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	var workspaces []Workspace
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/workspaces"}, &workspaces); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// StartWorkspace starts all mocks of a workspace
+func (c *Client) StartWorkspace(ctx context.Context, name string) ([]*MockAPI, error) {
+	var mocks []*MockAPI
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/workspaces/" + escape(name) + "/start"}, &mocks); err != nil {
+		return nil, err
+	}
+	return mocks, nil
+}
+
+// StopWorkspace stops all mocks of a workspace
+func (c *Client) StopWorkspace(ctx context.Context, name string) ([]*MockAPI, error) {
+	var mocks []*MockAPI
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/workspaces/" + escape(name) + "/stop"}, &mocks); err != nil {
+		return nil, err
+	}
+	return mocks, nil
+}
+
+// DeleteWorkspace deletes all mocks of a workspace and returns how many
+// were deleted
+func (c *Client) DeleteWorkspace(ctx context.Context, name string) (int, error) {
+	var result struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := c.do(ctx, request{method: http.MethodDelete, path: "/workspaces/" + escape(name)}, &result); err != nil {
+		return 0, err
+	}
+	return result.Deleted, nil
+}
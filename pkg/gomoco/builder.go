@@ -0,0 +1,315 @@
+package gomoco
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+)
+
+// HTTPBuilder defines an HTTP or HTTPS mock
+type HTTPBuilder struct {
+	server *Server
+	req    models.CreateMockAPIRequest
+}
+
+// HTTP starts defining an HTTP mock answering GET / with 200 and no body
+func (s *Server) HTTP() *HTTPBuilder {
+	return &HTTPBuilder{server: s, req: models.CreateMockAPIRequest{
+		Protocol: models.ProtocolHTTP,
+		Method:   http.MethodGet,
+		Path:     "/",
+	}}
+}
+
+// Name names the mock
+func (b *HTTPBuilder) Name(name string) *HTTPBuilder {
+	b.req.Name = name
+	return b
+}
+
+// Port sets the port, instead of a free one
+func (b *HTTPBuilder) Port(port int) *HTTPBuilder {
+	b.req.Port = port
+	return b
+}
+
+// Method sets the request method, e.g. POST
+func (b *HTTPBuilder) Method(method string) *HTTPBuilder {
+	b.req.Method = strings.ToUpper(method)
+	return b
+}
+
+// Path sets the request path. Templates such as /users/{id} match any
+// segment, and paths ending in / match their subtree.
+func (b *HTTPBuilder) Path(path string) *HTTPBuilder {
+	b.req.Path = path
+	return b
+}
+
+// Get answers GET requests to path
+func (b *HTTPBuilder) Get(path string) *HTTPBuilder {
+	return b.Method(http.MethodGet).Path(path)
+}
+
+// Post answers POST requests to path
+func (b *HTTPBuilder) Post(path string) *HTTPBuilder {
+	return b.Method(http.MethodPost).Path(path)
+}
+
+// Put answers PUT requests to path
+func (b *HTTPBuilder) Put(path string) *HTTPBuilder {
+	return b.Method(http.MethodPut).Path(path)
+}
+
+// Delete answers DELETE requests to path
+func (b *HTTPBuilder) Delete(path string) *HTTPBuilder {
+	return b.Method(http.MethodDelete).Path(path)
+}
+
+// Status sets the response status code
+func (b *HTTPBuilder) Status(code int) *HTTPBuilder {
+	b.req.StatusCode = code
+	return b
+}
+
+// Header adds a response header
+func (b *HTTPBuilder) Header(name, value string) *HTTPBuilder {
+	if b.req.Headers == nil {
+		b.req.Headers = make(map[string]string)
+	}
+	b.req.Headers[name] = value
+	return b
+}
+
+// ContentType sets the response content type
+func (b *HTTPBuilder) ContentType(contentType string) *HTTPBuilder {
+	b.req.ContentType = contentType
+	return b
+}
+
+// Body sets the response body
+func (b *HTTPBuilder) Body(body string) *HTTPBuilder {
+	b.req.Content = body
+	return b
+}
+
+// JSON sets a JSON response body. Strings and byte slices are sent as
+// they are, other values are marshaled.
+func (b *HTTPBuilder) JSON(body interface{}) *HTTPBuilder {
+	b.server.t.Helper()
+
+	switch v := body.(type) {
+	case string:
+		b.req.Content = v
+	case []byte:
+		b.req.Content = string(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			b.server.t.Fatalf("gomoco: failed to marshal JSON body: %v", err)
+			return b
+		}
+		b.req.Content = string(data)
+	}
+	return b.ContentType("application/json")
+}
+
+// Charset sets the response charset, UTF-8 or GBK
+func (b *HTTPBuilder) Charset(charset string) *HTTPBuilder {
+	b.req.Charset = charset
+	return b
+}
+
+// OpenAPI validates requests against an OpenAPI document. Invalid requests
+// are answered with status.
+func (b *HTTPBuilder) OpenAPI(specFile string, status int) *HTTPBuilder {
+	b.req.OpenAPISpec = specFile
+	b.req.ValidationStatus = status
+	return b
+}
+
+// TLS serves HTTPS with a certificate and key file
+func (b *HTTPBuilder) TLS(certFile, keyFile string) *HTTPBuilder {
+	b.req.Protocol = models.ProtocolHTTPS
+	b.req.CertFile = certFile
+	b.req.KeyFile = keyFile
+	return b
+}
+
+// Start starts the mock, failing the test if it cannot start
+func (b *HTTPBuilder) Start() *Mock {
+	b.server.t.Helper()
+	req := b.req
+	return b.server.start(&req)
+}
+
+// TCPBuilder defines a TCP mock
+type TCPBuilder struct {
+	server *Server
+	req    models.CreateMockAPIRequest
+}
+
+// TCP starts defining a TCP mock, which reads a request and answers with
+// the response
+func (s *Server) TCP() *TCPBuilder {
+	return &TCPBuilder{server: s, req: models.CreateMockAPIRequest{Protocol: models.ProtocolTCP}}
+}
+
+// Name names the mock
+func (b *TCPBuilder) Name(name string) *TCPBuilder {
+	b.req.Name = name
+	return b
+}
+
+// Port sets the port, instead of a free one
+func (b *TCPBuilder) Port(port int) *TCPBuilder {
+	b.req.Port = port
+	return b
+}
+
+// Response sets the bytes sent back to each connection
+func (b *TCPBuilder) Response(response string) *TCPBuilder {
+	b.req.Content = response
+	return b
+}
+
+// Charset sets the response charset, UTF-8 or GBK
+func (b *TCPBuilder) Charset(charset string) *TCPBuilder {
+	b.req.Charset = charset
+	return b
+}
+
+// Start starts the mock, failing the test if it cannot start
+func (b *TCPBuilder) Start() *Mock {
+	b.server.t.Helper()
+	req := b.req
+	return b.server.start(&req)
+}
+
+// FTPBuilder defines an FTP mock
+type FTPBuilder struct {
+	server *Server
+	req    models.CreateMockAPIRequest
+}
+
+// FTP starts defining a passive mode FTP mock serving a temporary
+// directory to user admin with password admin
+func (s *Server) FTP() *FTPBuilder {
+	return &FTPBuilder{server: s, req: models.CreateMockAPIRequest{
+		Protocol: models.ProtocolFTP,
+		FTPMode:  models.FTPModePassive,
+		FTPUser:  "admin",
+		FTPPass:  "admin",
+	}}
+}
+
+// Name names the mock
+func (b *FTPBuilder) Name(name string) *FTPBuilder {
+	b.req.Name = name
+	return b
+}
+
+// Port sets the control port, instead of a free one
+func (b *FTPBuilder) Port(port int) *FTPBuilder {
+	b.req.Port = port
+	return b
+}
+
+// Root sets the directory served, instead of a temporary one
+func (b *FTPBuilder) Root(dir string) *FTPBuilder {
+	b.req.FTPRootDir = dir
+	return b
+}
+
+// User sets the credentials clients log in with
+func (b *FTPBuilder) User(user, password string) *FTPBuilder {
+	b.req.FTPUser = user
+	b.req.FTPPass = password
+	return b
+}
+
+// Active switches to active mode
+func (b *FTPBuilder) Active() *FTPBuilder {
+	b.req.FTPMode = models.FTPModeActive
+	return b
+}
+
+// PassivePorts sets the passive data port range, e.g. "50000-50100"
+func (b *FTPBuilder) PassivePorts(portRange string) *FTPBuilder {
+	b.req.FTPMode = models.FTPModePassive
+	b.req.FTPPassivePortRange = portRange
+	return b
+}
+
+// Start starts the mock, failing the test if it cannot start
+func (b *FTPBuilder) Start() *Mock {
+	b.server.t.Helper()
+	req := b.req
+	if req.FTPRootDir == "" {
+		req.FTPRootDir = b.server.t.TempDir()
+	}
+	return b.server.start(&req)
+}
+
+// SFTPBuilder defines an SFTP mock
+type SFTPBuilder struct {
+	server *Server
+	req    models.CreateMockAPIRequest
+}
+
+// SFTP starts defining an SFTP mock serving a temporary directory to user
+// admin with password admin
+func (s *Server) SFTP() *SFTPBuilder {
+	return &SFTPBuilder{server: s, req: models.CreateMockAPIRequest{
+		Protocol: models.ProtocolSFTP,
+		SFTPUser: "admin",
+		SFTPPass: "admin",
+	}}
+}
+
+// Name names the mock
+func (b *SFTPBuilder) Name(name string) *SFTPBuilder {
+	b.req.Name = name
+	return b
+}
+
+// Port sets the port, instead of a free one
+func (b *SFTPBuilder) Port(port int) *SFTPBuilder {
+	b.req.Port = port
+	return b
+}
+
+// Root sets the directory served, instead of a temporary one
+func (b *SFTPBuilder) Root(dir string) *SFTPBuilder {
+	b.req.SFTPRootDir = dir
+	return b
+}
+
+// User sets the credentials clients log in with
+func (b *SFTPBuilder) User(user, password string) *SFTPBuilder {
+	b.req.SFTPUser = user
+	b.req.SFTPPass = password
+	return b
+}
+
+// HostKey sets the host key file, instead of a generated one
+func (b *SFTPBuilder) HostKey(file string) *SFTPBuilder {
+	b.req.SFTPHostKey = file
+	return b
+}
+
+// Start starts the mock, failing the test if it cannot start
+func (b *SFTPBuilder) Start() *Mock {
+	b.server.t.Helper()
+	req := b.req
+	if req.SFTPRootDir == "" {
+		req.SFTPRootDir = b.server.t.TempDir()
+	}
+	if req.SFTPHostKey == "" {
+		req.SFTPHostKey = filepath.Join(b.server.t.TempDir(), "host_key")
+	}
+	return b.server.start(&req)
+}
@@ -0,0 +1,132 @@
+// Package gomoco runs HTTP, HTTPS, TCP, FTP and SFTP mocks inside a Go test
+// process, without spawning the gomoco binary.
+//
+//	func TestClient(t *testing.T) {
+//		users := gomoco.NewHTTPMock(t).
+//			Get("/users/{id}").
+//			JSON(`{"id": 1, "name": "Ada"}`).
+//			Start()
+//
+//		resp, err := http.Get(users.URL() + "/users/1")
+//		...
+//		users.AssertCalled(1)
+//	}
+//
+// Mocks listen on free ports of 127.0.0.1 and are stopped when the test
+// finishes.
+package gomoco
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/server"
+	"github.com/Bahtya/Gomoco/internal/storage"
+)
+
+// TB is the part of testing.TB the mocks use
+type TB interface {
+	Helper()
+	Cleanup(func())
+	TempDir() string
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Server runs a set of mocks, stopped together when the test finishes
+type Server struct {
+	t       TB
+	manager *server.Manager
+	host    string
+}
+
+// New creates a server for the mocks of a test
+func New(t TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		t:    t,
+		host: "127.0.0.1",
+	}
+	s.manager = server.NewManager(storage.NewMemoryStorage(), server.Options{
+		BindHost:        s.host,
+		CaptureRequests: true,
+	})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.manager.Shutdown(ctx); err != nil {
+			t.Errorf("gomoco: %v", err)
+		}
+	})
+	return s
+}
+
+// NewHTTPMock starts defining an HTTP mock on a server of its own
+func NewHTTPMock(t TB) *HTTPBuilder {
+	t.Helper()
+	return New(t).HTTP()
+}
+
+// NewTCPMock starts defining a TCP mock on a server of its own
+func NewTCPMock(t TB) *TCPBuilder {
+	t.Helper()
+	return New(t).TCP()
+}
+
+// NewFTPMock starts defining an FTP mock on a server of its own
+func NewFTPMock(t TB) *FTPBuilder {
+	t.Helper()
+	return New(t).FTP()
+}
+
+// NewSFTPMock starts defining an SFTP mock on a server of its own
+func NewSFTPMock(t TB) *SFTPBuilder {
+	t.Helper()
+	return New(t).SFTP()
+}
+
+// start creates and starts a mock, failing the test if it cannot start
+func (s *Server) start(req *models.CreateMockAPIRequest) *Mock {
+	s.t.Helper()
+
+	if req.Charset == "" {
+		req.Charset = models.CharsetUTF8
+	}
+	if req.Name == "" {
+		req.Name = req.Protocol + " mock"
+	}
+
+	// The manager picks a free port if none is set
+	mock, err := s.manager.Create(req, models.SystemActor)
+	if err != nil {
+		s.t.Fatalf("gomoco: failed to start %s: %v", req.Name, err)
+		return nil
+	}
+	// FTP servers start listening in the background
+	if mock.Protocol == models.ProtocolFTP {
+		if err := waitListening(net.JoinHostPort(s.host, strconv.Itoa(mock.Port))); err != nil {
+			s.t.Fatalf("gomoco: failed to start %s: %v", req.Name, err)
+			return nil
+		}
+	}
+	return &Mock{server: s, id: mock.ID}
+}
+
+// waitListening waits until a TCP address accepts connections
+func waitListening(addr string) error {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
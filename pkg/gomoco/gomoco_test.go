@@ -0,0 +1,229 @@
+package gomoco
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHTTPMock(t *testing.T) {
+	users := NewHTTPMock(t).
+		Post("/users/{id}").
+		Status(http.StatusCreated).
+		Header("X-Request-Id", "42").
+		JSON(map[string]interface{}{"id": 1, "name": "Ada"}).
+		Start()
+
+	req, err := http.NewRequest(http.MethodPost, users.URL()+"/users/1?notify=true", strings.NewReader(`{"name":"Ada"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status %d, want 201", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Request-Id"); got != "42" {
+		t.Errorf("X-Request-Id %q, want 42", got)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		t.Errorf("content type %q", resp.Header.Get("Content-Type"))
+	}
+	if string(body) != `{"id":1,"name":"Ada"}` {
+		t.Errorf("body %s", body)
+	}
+
+	users.AssertCalled(1)
+	users.AssertBodyContains(`"name":"Ada"`)
+	users.AssertReceived(func(r Request) bool {
+		return r.Header.Get("Authorization") == "Bearer token"
+	})
+	last := users.LastRequest()
+	if last.Method != http.MethodPost || last.Path != "/users/1" || last.Query != "notify=true" {
+		t.Errorf("last request %s %s?%s", last.Method, last.Path, last.Query)
+	}
+
+	users.Reset()
+	users.AssertNotCalled()
+}
+
+func TestPortsAreAllocated(t *testing.T) {
+	s := New(t)
+	a := s.HTTP().Get("/a").Start()
+	b := s.HTTP().Get("/b").Start()
+	c := s.TCP().Start()
+	if a.Port() == 0 || a.Port() == b.Port() || b.Port() == c.Port() || a.Port() == c.Port() {
+		t.Errorf("ports %d, %d and %d", a.Port(), b.Port(), c.Port())
+	}
+}
+
+func TestCleanupStopsMocks(t *testing.T) {
+	var addr string
+	t.Run("mock", func(t *testing.T) {
+		mock := NewHTTPMock(t).Body("ok").Start()
+		addr = mock.Addr()
+		resp, err := http.Get(mock.URL())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Errorf("mock on %s still listening after its test finished", addr)
+	}
+}
+
+func TestStop(t *testing.T) {
+	mock := NewTCPMock(t).Start()
+	mock.Stop()
+	if conn, err := net.DialTimeout("tcp", mock.Addr(), time.Second); err == nil {
+		conn.Close()
+		t.Errorf("stopped mock on %s still listening", mock.Addr())
+	}
+}
+
+// recorder is a TB that records reported errors instead of failing
+type recorder struct {
+	*testing.T
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertionsReportErrors(t *testing.T) {
+	r := &recorder{T: t}
+	mock := NewHTTPMock(r).Name("orders").Start()
+
+	if mock.AssertCalled(1) {
+		t.Error("AssertCalled(1) passed without requests")
+	}
+	if mock.AssertBodyContains("x") {
+		t.Error("AssertBodyContains passed without requests")
+	}
+	if mock.AssertReceived(func(Request) bool { return true }) {
+		t.Error("AssertReceived passed without requests")
+	}
+	if len(r.errors) != 3 || !strings.Contains(r.errors[0], "orders received 0 requests, want 1") {
+		t.Errorf("reported %q", r.errors)
+	}
+}
+
+func TestTCPMock(t *testing.T) {
+	mock := NewTCPMock(t).Response("pong").Start()
+
+	conn, err := net.Dial("tcp", mock.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	response, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response) != "pong" {
+		t.Errorf("response %q, want pong", response)
+	}
+
+	mock.AssertCalled(1)
+	mock.AssertBodyContains("ping")
+}
+
+func TestFTPMock(t *testing.T) {
+	mock := NewFTPMock(t).User("tester", "secret").Start()
+	mock.WriteFile("in/report.csv", []byte("a,b\n"))
+
+	conn, err := ftp.Dial(mock.Addr(), ftp.DialWithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Quit()
+	if err := conn.Login("tester", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := conn.Retr("in/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp)
+	resp.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n" {
+		t.Errorf("downloaded %q", data)
+	}
+
+	if err := conn.Stor("upload.txt", bytes.NewReader([]byte("uploaded"))); err != nil {
+		t.Fatal(err)
+	}
+	mock.AssertFile("upload.txt", []byte("uploaded"))
+}
+
+func TestSFTPMock(t *testing.T) {
+	mock := NewSFTPMock(t).User("tester", "secret").Start()
+	mock.WriteFile("in/report.csv", []byte("a,b\n"))
+
+	sshConn, err := ssh.Dial("tcp", mock.Addr(), &ssh.ClientConfig{
+		User:            "tester",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sshConn.Close()
+	client, err := sftp.NewClient(sshConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	f, err := client.Open("in/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n" {
+		t.Errorf("downloaded %q", data)
+	}
+
+	f, err = client.Create("upload.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("uploaded")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mock.AssertFile("upload.txt", []byte("uploaded"))
+}
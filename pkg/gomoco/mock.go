@@ -0,0 +1,218 @@
+package gomoco
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Bahtya/Gomoco/internal/models"
+	"github.com/Bahtya/Gomoco/internal/server"
+)
+
+// Request is a request received by an HTTP or TCP mock. TCP requests only
+// have a time, remote address and body.
+type Request = server.Request
+
+// Mock is a running mock
+type Mock struct {
+	server *Server
+	id     string
+}
+
+// definition returns the current definition of the mock
+func (m *Mock) definition() *models.MockAPI {
+	m.server.t.Helper()
+
+	mock, err := m.server.manager.Get(m.id)
+	if err != nil {
+		m.server.t.Fatalf("gomoco: mock %s: %v", m.id, err)
+		return &models.MockAPI{}
+	}
+	return mock
+}
+
+// ID returns the ID of the mock
+func (m *Mock) ID() string {
+	return m.id
+}
+
+// Port returns the port the mock listens on
+func (m *Mock) Port() int {
+	m.server.t.Helper()
+	return m.definition().Port
+}
+
+// Addr returns the host:port the mock listens on
+func (m *Mock) Addr() string {
+	m.server.t.Helper()
+	return net.JoinHostPort(m.server.host, strconv.Itoa(m.Port()))
+}
+
+// URL returns the base URL of an HTTP or HTTPS mock, e.g.
+// http://127.0.0.1:41234
+func (m *Mock) URL() string {
+	m.server.t.Helper()
+
+	mock := m.definition()
+	switch mock.Protocol {
+	case models.ProtocolHTTP, models.ProtocolHTTPS:
+		return fmt.Sprintf("%s://%s", mock.Protocol, m.Addr())
+	}
+	m.server.t.Fatalf("gomoco: %s mocks have no URL", mock.Protocol)
+	return ""
+}
+
+// Stop stops the mock before the test ends
+func (m *Mock) Stop() {
+	m.server.t.Helper()
+
+	if _, err := m.server.manager.Stop(m.id, models.SystemActor); err != nil {
+		m.server.t.Fatalf("gomoco: failed to stop mock: %v", err)
+	}
+}
+
+// Requests returns the requests the mock received, oldest first
+func (m *Mock) Requests() []Request {
+	m.server.t.Helper()
+
+	requests, err := m.server.manager.Requests(m.id)
+	if err != nil {
+		m.server.t.Fatalf("gomoco: mock %s: %v", m.id, err)
+	}
+	return requests
+}
+
+// LastRequest returns the most recent request. It fails the test if there
+// is none.
+func (m *Mock) LastRequest() Request {
+	m.server.t.Helper()
+
+	requests := m.Requests()
+	if len(requests) == 0 {
+		m.server.t.Fatalf("gomoco: %s received no requests", m.definition().Name)
+		return Request{}
+	}
+	return requests[len(requests)-1]
+}
+
+// Calls returns the number of requests the mock received
+func (m *Mock) Calls() int {
+	m.server.t.Helper()
+	return len(m.Requests())
+}
+
+// Reset forgets the requests received so far
+func (m *Mock) Reset() {
+	m.server.t.Helper()
+
+	if err := m.server.manager.ClearRequests(m.id); err != nil {
+		m.server.t.Fatalf("gomoco: mock %s: %v", m.id, err)
+	}
+}
+
+// AssertCalled reports an error unless the mock received exactly n requests
+func (m *Mock) AssertCalled(n int) bool {
+	m.server.t.Helper()
+
+	if calls := m.Calls(); calls != n {
+		m.server.t.Errorf("gomoco: %s received %d requests, want %d", m.definition().Name, calls, n)
+		return false
+	}
+	return true
+}
+
+// AssertNotCalled reports an error if the mock received any request
+func (m *Mock) AssertNotCalled() bool {
+	m.server.t.Helper()
+	return m.AssertCalled(0)
+}
+
+// AssertReceived reports an error unless a request matches, e.g.
+//
+//	mock.AssertReceived(func(r gomoco.Request) bool {
+//		return r.Header.Get("Authorization") == "Bearer token"
+//	})
+func (m *Mock) AssertReceived(match func(Request) bool) bool {
+	m.server.t.Helper()
+
+	for _, request := range m.Requests() {
+		if match(request) {
+			return true
+		}
+	}
+	m.server.t.Errorf("gomoco: %s received no matching request", m.definition().Name)
+	return false
+}
+
+// AssertBodyContains reports an error unless a request body contains s
+func (m *Mock) AssertBodyContains(s string) bool {
+	m.server.t.Helper()
+
+	for _, request := range m.Requests() {
+		if strings.Contains(string(request.Body), s) {
+			return true
+		}
+	}
+	m.server.t.Errorf("gomoco: %s received no request with a body containing %q", m.definition().Name, s)
+	return false
+}
+
+// Root returns the directory served by an FTP or SFTP mock
+func (m *Mock) Root() string {
+	m.server.t.Helper()
+
+	mock := m.definition()
+	switch mock.Protocol {
+	case models.ProtocolFTP:
+		return mock.FTPRootDir
+	case models.ProtocolSFTP:
+		return mock.SFTPRootDir
+	}
+	m.server.t.Fatalf("gomoco: %s mocks serve no files", mock.Protocol)
+	return ""
+}
+
+// WriteFile puts a file into the directory served by an FTP or SFTP mock
+func (m *Mock) WriteFile(name string, data []byte) {
+	m.server.t.Helper()
+
+	path := filepath.Join(m.Root(), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		m.server.t.Fatalf("gomoco: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		m.server.t.Fatalf("gomoco: %v", err)
+	}
+}
+
+// ReadFile reads a file, e.g. one a client uploaded, from the directory
+// served by an FTP or SFTP mock
+func (m *Mock) ReadFile(name string) []byte {
+	m.server.t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(m.Root(), filepath.FromSlash(name)))
+	if err != nil {
+		m.server.t.Fatalf("gomoco: %v", err)
+	}
+	return data
+}
+
+// AssertFile reports an error unless the directory served by an FTP or
+// SFTP mock holds a file with the given content
+func (m *Mock) AssertFile(name string, want []byte) bool {
+	m.server.t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(m.Root(), filepath.FromSlash(name)))
+	if err != nil {
+		m.server.t.Errorf("gomoco: %v", err)
+		return false
+	}
+	if string(data) != string(want) {
+		m.server.t.Errorf("gomoco: %s holds %q, want %q", name, data, want)
+		return false
+	}
+	return true
+}